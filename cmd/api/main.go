@@ -4,15 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/purga"
 	"go-gorilla-autos/internal/server"
 )
 
-func gracefulShutdown(apiServer *http.Server, done chan bool) {
+func gracefulShutdown(apiServer *http.Server, db database.Service, done chan bool) {
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -30,6 +36,13 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	// Close the database only after the server stopped handling requests
+	dbCtx, dbCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer dbCancel()
+	if err := db.Close(dbCtx); err != nil {
+		log.Printf("Error closing database connection: %v", err)
+	}
+
 	log.Println("Server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
@@ -38,19 +51,42 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 
 func main() {
 
-	server := server.NewServer()
+	// Structured JSON logs; the standard log package also writes through this logger
+	slog.SetDefault(logger.New(os.Stdout))
+
+	cfg, err := database.ConfigDesdeEntorno()
+	if err != nil {
+		log.Fatalf("Invalid database configuration: %v", err)
+	}
+	purgaCfg, err := purga.ConfigDesdeEntorno()
+	if err != nil {
+		log.Fatalf("Invalid trash purge configuration: %v", err)
+	}
+	db, err := database.New(cfg)
+	if err != nil {
+		log.Fatalf("Error connecting to MongoDB: %v", err)
+	}
+
+	server := server.NewServer(db)
+
+	// Eliminar definitivamente los autos que llevan demasiado tiempo en la papelera
+	purgaCtx, detenerPurga := context.WithCancel(context.Background())
+	go purga.Iniciar(purgaCtx, repository.NewMongo(db), purgaCfg)
 
 	// Create a done channel to signal when the shutdown is complete
 	done := make(chan bool, 1)
 
 	// Run graceful shutdown in a separate goroutine
-	go gracefulShutdown(server, done)
+	go gracefulShutdown(server, db, done)
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		panic(fmt.Sprintf("http server error: %s", err))
 	}
 
+	// Detener la purga antes de que se cierre la base
+	detenerPurga()
+
 	// Wait for the graceful shutdown to complete
 	<-done
 	log.Println("Graceful shutdown complete.")
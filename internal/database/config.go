@@ -0,0 +1,134 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Valores por defecto de la configuración de la base
+const (
+	NombreDefault           = "autos_db"
+	TimeoutConexionDefault  = 10 * time.Second
+	TimeoutSeleccionDefault = 5 * time.Second
+	MaxPoolDefault          = 100
+	ReintentosDefault       = 5
+	EsperaReintentoDefault  = time.Second
+)
+
+// Config es la configuración de la conexión a MongoDB. Los campos en cero toman el
+// valor por defecto.
+type Config struct {
+	// URI es la cadena de conexión de MongoDB
+	URI string
+	// Nombre es el nombre de la base que usa la aplicación
+	Nombre string
+	// TimeoutConexion es el tiempo máximo para abrir cada conexión con el servidor
+	TimeoutConexion time.Duration
+	// TimeoutSeleccion es el tiempo máximo para encontrar un servidor disponible
+	TimeoutSeleccion time.Duration
+	// MaxPool y MinPool limitan la cantidad de conexiones del pool
+	MaxPool uint64
+	MinPool uint64
+	// Reintentos es la cantidad de intentos de conexión al arrancar
+	Reintentos int
+	// EsperaReintento es la espera antes del segundo intento; se duplica en cada intento siguiente
+	EsperaReintento time.Duration
+}
+
+// ConfigDesdeEntorno arma la configuración con las variables de entorno MONGODB_*,
+// usando los valores por defecto para las que no están definidas
+func ConfigDesdeEntorno() (Config, error) {
+	cfg := Config{
+		URI:    os.Getenv("MONGODB_URI"),
+		Nombre: os.Getenv("MONGODB_DATABASE"),
+	}.conDefaults()
+
+	var err error
+	if cfg.TimeoutConexion, err = duracionEntorno("MONGODB_CONNECT_TIMEOUT", cfg.TimeoutConexion); err != nil {
+		return Config{}, err
+	}
+	if cfg.TimeoutSeleccion, err = duracionEntorno("MONGODB_SERVER_SELECTION_TIMEOUT", cfg.TimeoutSeleccion); err != nil {
+		return Config{}, err
+	}
+	if cfg.EsperaReintento, err = duracionEntorno("MONGODB_RETRY_BACKOFF", cfg.EsperaReintento); err != nil {
+		return Config{}, err
+	}
+	if cfg.MaxPool, err = enteroEntorno("MONGODB_MAX_POOL_SIZE", cfg.MaxPool); err != nil {
+		return Config{}, err
+	}
+	if cfg.MinPool, err = enteroEntorno("MONGODB_MIN_POOL_SIZE", cfg.MinPool); err != nil {
+		return Config{}, err
+	}
+	reintentos, err := enteroEntorno("MONGODB_CONNECT_RETRIES", uint64(cfg.Reintentos))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Reintentos = int(reintentos)
+
+	return cfg, cfg.Validar()
+}
+
+// conDefaults completa con los valores por defecto los campos sin definir
+func (c Config) conDefaults() Config {
+	if c.Nombre == "" {
+		c.Nombre = NombreDefault
+	}
+	if c.TimeoutConexion == 0 {
+		c.TimeoutConexion = TimeoutConexionDefault
+	}
+	if c.TimeoutSeleccion == 0 {
+		c.TimeoutSeleccion = TimeoutSeleccionDefault
+	}
+	if c.MaxPool == 0 {
+		c.MaxPool = MaxPoolDefault
+	}
+	if c.Reintentos == 0 {
+		c.Reintentos = ReintentosDefault
+	}
+	if c.EsperaReintento == 0 {
+		c.EsperaReintento = EsperaReintentoDefault
+	}
+	return c
+}
+
+// Validar verifica que la configuración permita conectarse
+func (c Config) Validar() error {
+	if c.URI == "" {
+		return fmt.Errorf("MONGODB_URI no está definida")
+	}
+	if c.MaxPool > 0 && c.MinPool > c.MaxPool {
+		return fmt.Errorf("MONGODB_MIN_POOL_SIZE (%d) no puede ser mayor que MONGODB_MAX_POOL_SIZE (%d)", c.MinPool, c.MaxPool)
+	}
+	if c.Reintentos < 1 {
+		return fmt.Errorf("MONGODB_CONNECT_RETRIES debe ser al menos 1")
+	}
+	return nil
+}
+
+// duracionEntorno lee una duración como "5s" o "500ms", o devuelve el valor por defecto
+func duracionEntorno(clave string, defecto time.Duration) (time.Duration, error) {
+	valor := os.Getenv(clave)
+	if valor == "" {
+		return defecto, nil
+	}
+	d, err := time.ParseDuration(valor)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s inválido: %q", clave, valor)
+	}
+	return d, nil
+}
+
+// enteroEntorno lee un entero no negativo, o devuelve el valor por defecto
+func enteroEntorno(clave string, defecto uint64) (uint64, error) {
+	valor := os.Getenv(clave)
+	if valor == "" {
+		return defecto, nil
+	}
+	n, err := strconv.ParseUint(valor, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s inválido: %q", clave, valor)
+	}
+	return n, nil
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigDesdeEntorno(t *testing.T) {
+	t.Setenv("MONGODB_URI", "mongodb://localhost:27017")
+	t.Setenv("MONGODB_DATABASE", "")
+	t.Setenv("MONGODB_CONNECT_TIMEOUT", "3s")
+	t.Setenv("MONGODB_MAX_POOL_SIZE", "20")
+	t.Setenv("MONGODB_CONNECT_RETRIES", "")
+
+	cfg, err := ConfigDesdeEntorno()
+	if err != nil {
+		t.Fatalf("ConfigDesdeEntorno: %v", err)
+	}
+	if cfg.Nombre != NombreDefault || cfg.TimeoutConexion != 3*time.Second || cfg.MaxPool != 20 || cfg.Reintentos != ReintentosDefault {
+		t.Errorf("config = %+v", cfg)
+	}
+}
+
+func TestConfigDesdeEntornoInvalida(t *testing.T) {
+	casos := map[string]map[string]string{
+		"sin uri":           {"MONGODB_URI": ""},
+		"timeout inválido":  {"MONGODB_CONNECT_TIMEOUT": "diez"},
+		"pool inválido":     {"MONGODB_MAX_POOL_SIZE": "-1"},
+		"pool mínimo mayor": {"MONGODB_MAX_POOL_SIZE": "5", "MONGODB_MIN_POOL_SIZE": "10"},
+		"sin reintentos":    {"MONGODB_CONNECT_RETRIES": "0"},
+	}
+	for nombre, entorno := range casos {
+		t.Run(nombre, func(t *testing.T) {
+			t.Setenv("MONGODB_URI", "mongodb://localhost:27017")
+			for clave, valor := range entorno {
+				t.Setenv(clave, valor)
+			}
+			if _, err := ConfigDesdeEntorno(); err == nil {
+				t.Error("se esperaba un error")
+			}
+		})
+	}
+}
+
+func TestNewReintentaYFalla(t *testing.T) {
+	cfg := Config{
+		URI:              "mongodb://127.0.0.1:1/?connect=direct",
+		TimeoutSeleccion: 50 * time.Millisecond,
+		Reintentos:       2,
+		EsperaReintento:  time.Millisecond,
+	}
+	_, err := New(cfg)
+	if err == nil || !strings.Contains(err.Error(), "2 intentos") {
+		t.Errorf("err = %v, se esperaba un error después de 2 intentos", err)
+	}
+}
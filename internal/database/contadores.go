@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ColeccionContadores guarda las secuencias atómicas, un documento por secuencia
+const ColeccionContadores = "contadores"
+
+// SiguienteSecuencia incrementa atómicamente la secuencia indicada y devuelve el nuevo
+// valor. Si la secuencia no existe la crea empezando en 1.
+func SiguienteSecuencia(ctx context.Context, db Service, nombre string) (int64, error) {
+	var contador struct {
+		Valor int64 `bson:"valor"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := db.Collection(ColeccionContadores).FindOneAndUpdate(ctx,
+		bson.M{"_id": nombre},
+		bson.M{"$inc": bson.M{"valor": int64(1)}},
+		opts,
+	).Decode(&contador)
+	if err != nil {
+		return 0, fmt.Errorf("error incrementando la secuencia %s: %w", nombre, err)
+	}
+	return contador.Valor, nil
+}
+
+// SecuenciaStockID es el nombre de la secuencia de stock_id para un prefijo
+func SecuenciaStockID(prefijo string) string {
+	return "stock_id_" + prefijo
+}
+
+// migrarContadoresStockID inicializa las secuencias de stock_id con el número más alto
+// usado por cada prefijo, para que los autos existentes conserven su stock_id y los
+// nuevos no lo repitan. Con $max se puede correr en cada arranque sin retroceder.
+func migrarContadoresStockID(ctx context.Context, db Service) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"stock_id": bson.M{"$regex": "^[A-Z][0-9]+$"}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":    bson.M{"$substrBytes": bson.A{"$stock_id", 0, 1}},
+			"maximo": bson.M{"$max": bson.M{"$toLong": bson.M{"$substrBytes": bson.A{"$stock_id", 1, -1}}}},
+		}}},
+	}
+	cursor, err := db.Collection("autos").Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("error buscando los stock_id existentes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var maximos []struct {
+		Prefijo string `bson:"_id"`
+		Maximo  int64  `bson:"maximo"`
+	}
+	if err := cursor.All(ctx, &maximos); err != nil {
+		return fmt.Errorf("error decodificando los stock_id existentes: %w", err)
+	}
+
+	contadores := db.Collection(ColeccionContadores)
+	for _, m := range maximos {
+		_, err := contadores.UpdateOne(ctx,
+			bson.M{"_id": SecuenciaStockID(m.Prefijo)},
+			bson.M{"$max": bson.M{"valor": m.Maximo}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return fmt.Errorf("error inicializando la secuencia de %s: %w", m.Prefijo, err)
+		}
+	}
+	return nil
+}
@@ -2,44 +2,87 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"log"
-	"os"
+	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 type Service interface {
 	Collection(name string) *mongo.Collection
+	// Health verifica que el servidor responda
+	Health(ctx context.Context) error
+	// Close cierra las conexiones del pool esperando las operaciones en curso
+	Close(ctx context.Context) error
 }
 
 type service struct {
 	client *mongo.Client
+	db     *mongo.Database
 }
 
-func New() Service {
-	// Cargar variables de entorno desde el archivo .env
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+// New se conecta a MongoDB con la configuración indicada; los campos sin definir toman
+// los valores por defecto. Como mongo.Connect no verifica la conexión, cada intento hace
+// un ping; si falla se reintenta con espera exponencial.
+func New(cfg Config) (Service, error) {
+	cfg = cfg.conDefaults()
+	if err := cfg.Validar(); err != nil {
+		return nil, err
 	}
 
-	// Obtener el URI de MongoDB desde las variables de entorno
-	uri := os.Getenv("MONGODB_URI")
-	if uri == "" {
-		log.Fatal("MONGODB_URI is not set in the environment")
+	clientOptions := options.Client().
+		ApplyURI(cfg.URI).
+		SetConnectTimeout(cfg.TimeoutConexion).
+		SetServerSelectionTimeout(cfg.TimeoutSeleccion).
+		SetMaxPoolSize(cfg.MaxPool).
+		SetMinPoolSize(cfg.MinPool)
+
+	espera := cfg.EsperaReintento
+	var err error
+	for intento := 1; intento <= cfg.Reintentos; intento++ {
+		var client *mongo.Client
+		client, err = conectar(clientOptions, cfg.TimeoutConexion+cfg.TimeoutSeleccion)
+		if err == nil {
+			return &service{client: client, db: client.Database(cfg.Nombre)}, nil
+		}
+		if intento == cfg.Reintentos {
+			break
+		}
+		log.Printf("Error connecting to MongoDB (intento %d de %d), reintentando en %s: %v", intento, cfg.Reintentos, espera, err)
+		time.Sleep(espera)
+		espera *= 2
 	}
+	return nil, fmt.Errorf("no se pudo conectar a MongoDB después de %d intentos: %w", cfg.Reintentos, err)
+}
 
-	clientOptions := options.Client().ApplyURI(uri)
-	client, err := mongo.Connect(context.Background(), clientOptions)
+// conectar abre el cliente y verifica la conexión con un ping. Si el ping falla
+// desconecta el cliente para no dejar el pool abierto.
+func conectar(clientOptions *options.ClientOptions, timeout time.Duration) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
-		log.Fatalf("Error connecting to MongoDB: %v", err)
+		return nil, err
 	}
-
-	return &service{client: client}
+	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
+	return client, nil
 }
 
 func (s *service) Collection(name string) *mongo.Collection {
-	return s.client.Database("autos_db").Collection(name)
+	return s.db.Collection(name)
+}
+
+func (s *service) Health(ctx context.Context) error {
+	return s.client.Ping(ctx, readpref.Primary())
+}
+
+func (s *service) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
 }
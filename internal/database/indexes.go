@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes crea los índices que necesita la aplicación si todavía no existen
+// y completa los campos derivados de los autos guardados antes de que existieran
+func EnsureIndexes(ctx context.Context, db Service) error {
+	autos := db.Collection("autos")
+
+	// Índice de texto para la búsqueda libre del catálogo. El idioma español y la
+	// versión 3 del índice hacen que "automática" y "automatica" coincidan.
+	textIndex := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "marca", Value: "text"},
+			{Key: "modelo", Value: "text"},
+			{Key: "version", Value: "text"},
+			{Key: "equipamiento_destacado", Value: "text"},
+			{Key: "caracteristicas_texto", Value: "text"},
+		},
+		Options: options.Index().
+			SetName("autos_texto").
+			SetDefaultLanguage("spanish").
+			SetWeights(bson.D{
+				{Key: "marca", Value: 10},
+				{Key: "modelo", Value: 10},
+				{Key: "version", Value: 5},
+				{Key: "equipamiento_destacado", Value: 3},
+				{Key: "caracteristicas_texto", Value: 1},
+			}),
+	}
+	if _, err := autos.Indexes().CreateOne(ctx, textIndex); err != nil {
+		return fmt.Errorf("error creando el índice de texto: %w", err)
+	}
+
+	// Índice para consultar el historial de cambios de un auto por fecha
+	historyIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "stock_id", Value: 1}, {Key: "fecha", Value: -1}},
+		Options: options.Index().SetName("historial_stock_fecha"),
+	}
+	if _, err := db.Collection("auto_history").Indexes().CreateOne(ctx, historyIndex); err != nil {
+		return fmt.Errorf("error creando el índice del historial: %w", err)
+	}
+
+	// Índice para quitar una campaña de los autos que la tienen
+	campaniaIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "campania.campania_id", Value: 1}},
+		Options: options.Index().SetName("autos_campania").SetSparse(true),
+	}
+	if _, err := autos.Indexes().CreateOne(ctx, campaniaIndex); err != nil {
+		return fmt.Errorf("error creando el índice de campañas: %w", err)
+	}
+
+	// Índice para listar y purgar la papelera
+	papeleraIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "deleted_at", Value: -1}},
+		Options: options.Index().SetName("autos_papelera").SetSparse(true),
+	}
+	if _, err := autos.Indexes().CreateOne(ctx, papeleraIndex); err != nil {
+		return fmt.Errorf("error creando el índice de la papelera: %w", err)
+	}
+
+	// Una sola tasa vigente por moneda, y su historial ordenado por fecha
+	tipoCambioIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "moneda", Value: 1}},
+		Options: options.Index().SetName("tipos_cambio_moneda").SetUnique(true),
+	}
+	if _, err := db.Collection("tipos_cambio").Indexes().CreateOne(ctx, tipoCambioIndex); err != nil {
+		return fmt.Errorf("error creando el índice de tipos de cambio: %w", err)
+	}
+	tipoCambioHistorialIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "moneda", Value: 1}, {Key: "actualizado_en", Value: -1}},
+		Options: options.Index().SetName("tipos_cambio_historial_moneda_fecha"),
+	}
+	if _, err := db.Collection("tipos_cambio_historial").Indexes().CreateOne(ctx, tipoCambioHistorialIndex); err != nil {
+		return fmt.Errorf("error creando el índice del historial de tipos de cambio: %w", err)
+	}
+
+	if err := migrarPrecioLista(ctx, autos); err != nil {
+		return err
+	}
+
+	if err := backfillCaracteristicasTexto(ctx, autos); err != nil {
+		return err
+	}
+
+	if err := migrarContadoresStockID(ctx, db); err != nil {
+		return err
+	}
+
+	// El índice único evita stock_id repetidos aunque se creen autos en paralelo. Va al
+	// final porque falla si ya hay duplicados y no debe frenar las demás migraciones.
+	stockIDIndex := mongo.IndexModel{
+		Keys:    bson.D{{Key: "stock_id", Value: 1}},
+		Options: options.Index().SetName("autos_stock_id").SetUnique(true),
+	}
+	if _, err := autos.Indexes().CreateOne(ctx, stockIDIndex); err != nil {
+		return fmt.Errorf("error creando el índice único de stock_id, revisar si hay duplicados: %w", err)
+	}
+	return nil
+}
+
+// migrarPrecioLista separa el precio de lista del descuento en los autos guardados antes
+// de que existiera precio_lista, cuando el descuento se restaba directamente del precio
+func migrarPrecioLista(ctx context.Context, autos *mongo.Collection) error {
+	descuento := bson.M{"$ifNull": bson.A{"$descuento", 0}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"precio_lista": bson.M{"$add": bson.A{"$precio", descuento}},
+			"promocion": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{descuento, 0}},
+				bson.M{"tipo": models.DescuentoMonto, "valor": "$descuento", "creado_en": "$updated_at"},
+				"$$REMOVE",
+			}},
+		}}},
+	}
+	if _, err := autos.UpdateMany(ctx, bson.M{"precio_lista": bson.M{"$exists": false}}, update); err != nil {
+		return fmt.Errorf("error migrando precio_lista: %w", err)
+	}
+	return nil
+}
+
+// backfillCaracteristicasTexto completa caracteristicas_texto en los autos que no lo tienen
+func backfillCaracteristicasTexto(ctx context.Context, autos *mongo.Collection) error {
+	cursor, err := autos.Find(ctx, bson.M{"caracteristicas_texto": bson.M{"$exists": false}})
+	if err != nil {
+		return fmt.Errorf("error buscando autos sin caracteristicas_texto: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var auto models.Auto
+		if err := cursor.Decode(&auto); err != nil {
+			return fmt.Errorf("error decodificando auto: %w", err)
+		}
+		auto.ActualizarCaracteristicasTexto()
+		update := bson.M{"$set": bson.M{"caracteristicas_texto": auto.CaracteristicasTexto}}
+		if _, err := autos.UpdateOne(ctx, bson.M{"stock_id": auto.StockID}, update); err != nil {
+			return fmt.Errorf("error actualizando auto %s: %w", auto.StockID, err)
+		}
+	}
+	return cursor.Err()
+}
@@ -3,7 +3,11 @@ package models
 import (
 	"fmt"
 	"math/rand"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,6 +25,7 @@ type Auto struct {
 	Año                            int                `json:"año" bson:"año" binding:"required"`
 	Kilometraje                    int                `json:"kilometraje" bson:"kilometraje" binding:"required"`
 	Precio                         float64            `json:"precio" bson:"precio" binding:"required"`
+	PrecioLista                    float64            `json:"precio_lista" bson:"precio_lista"`
 	Ciudad                         string             `json:"ciudad" bson:"ciudad" binding:"required"`
 	Transmision                    string             `json:"transmision" bson:"transmision" binding:"required"`
 	Traccion                       string             `json:"traccion" bson:"traccion" binding:"required"`
@@ -32,8 +37,16 @@ type Auto struct {
 	Featured                       bool               `json:"featured" bson:"featured"`
 	Estado                         string             `json:"estado" bson:"estado"`
 	Descuento                      float64            `json:"descuento" bson:"descuento"`
+	Promocion                      *Promocion         `json:"promocion" bson:"promocion,omitempty"`
+	Campania                       *PromocionCampania `json:"campania" bson:"campania,omitempty"`
+	PrecioAnterior                 float64            `json:"precio_anterior,omitempty" bson:"precio_anterior,omitempty"`
+	BajoDePrecio                   bool               `json:"bajo_de_precio" bson:"bajo_de_precio"`
+	BajoDePrecioEn                 time.Time          `json:"bajo_de_precio_en,omitempty" bson:"bajo_de_precio_en,omitempty"`
+	HistorialPrecios               []PrecioHistorico  `json:"historial_precios" bson:"historial_precios,omitempty"`
 	CreatedAt                      time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt                      time.Time          `json:"updated_at" bson:"updated_at"`
+	Revision                       int64              `json:"revision" bson:"revision,omitempty"`
+	DeletedAt                      *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 	Imagen_Portada                 string             `json:"imagen_portada" bson:"imagen_portada"`
 	Imagenes_Imperfecciones        []string           `json:"imagenes_imperfecciones" bson:"imagenes_imperfecciones"`
 	EquipamientoDestacado          []string           `json:"equipamiento_destacado" bson:"equipamiento_destacado"`
@@ -43,11 +56,14 @@ type Auto struct {
 	CaracteristicasConfort         map[string]string  `json:"caracteristicas_confort" bson:"caracteristicas_confort"`
 	CaracteristicasInterior        map[string]string  `json:"caracteristicas_interior" bson:"caracteristicas_interior"`
 	CaracteristicasEntretenimiento map[string]string  `json:"caracteristicas_entretenimiento" bson:"caracteristicas_entretenimiento"`
+	CaracteristicasTexto           []string           `json:"-" bson:"caracteristicas_texto"`
 	ReservadoPor                   *ReservadoInfo     `json:"reservado_por" bson:"reservado_por,omitempty"`
 	VendidoPor                     *VendidoInfo       `json:"vendido_por" bson:"vendido_por,omitempty"`
 	EnNegociacion                  *NegociacionInfo   `json:"en_negociacion" bson:"en_negociacion,omitempty"`
 	EnMantenimiento                *MantenimientoInfo `json:"en_mantenimiento" bson:"en_mantenimiento,omitempty"`
 	Reservas                       []Reserva          `json:"reservas" bson:"reservas"`
+	EstadoActualizadoEn            time.Time          `json:"estado_actualizado_en" bson:"estado_actualizado_en,omitempty"`
+	HistorialEstados               []TransicionEstado `json:"historial_estados" bson:"historial_estados,omitempty"`
 	TipoCombustible                string             `json:"tipo_combustible" bson:"tipo_combustible" binding:"required"`
 	Moneda                         string             `json:"moneda" bson:"moneda" binding:"required"`
 }
@@ -89,96 +105,64 @@ type Reserva struct {
 	FechaHora  time.Time `json:"fecha_hora"`
 }
 
-func (a *Auto) ValidateRequired() ([]string, error) {
-	missingFields := []string{}
-
-	// Validar campos requeridos
-	if a.Marca == "" {
-		missingFields = append(missingFields, "marca")
-	}
-	if a.Modelo == "" {
-		missingFields = append(missingFields, "modelo")
-	}
-	if a.TipoVenta == "" {
-		missingFields = append(missingFields, "tipo_venta")
-	}
-	if a.TipoCombustible == "" {
-		missingFields = append(missingFields, "tipo_combustible")
-	}
-	if a.Moneda == "" {
-		missingFields = append(missingFields, "moneda")
-	}
-	if a.Año == 0 {
-		missingFields = append(missingFields, "año")
-	}
-	if a.Kilometraje < 0 {
-		missingFields = append(missingFields, "kilometraje")
-	}
-	if a.Precio <= 0 {
-		missingFields = append(missingFields, "precio")
-	}
-	if a.Ciudad == "" {
-		missingFields = append(missingFields, "ciudad")
-	}
-	if a.Transmision == "" {
-		missingFields = append(missingFields, "transmision")
-	}
-	if a.Traccion == "" {
-		missingFields = append(missingFields, "traccion")
-	}
-	if a.Sucursal == "" {
-		missingFields = append(missingFields, "sucursal")
-	}
-	if a.Version == "" {
-		missingFields = append(missingFields, "version")
-	}
-	if a.Garantia == "" {
-		missingFields = append(missingFields, "garantia")
-	}
+// ActualizarCaracteristicasTexto aplana los valores de los mapas de características en
+// caracteristicas_texto, que es el campo que usa el índice de búsqueda de texto
+func (a *Auto) ActualizarCaracteristicasTexto() {
+	texto := []string{}
+	for _, caracteristicas := range []map[string]string{
+		a.CaracteristicasGeneral,
+		a.CaracteristicasExterior,
+		a.CaracteristicasSeguridad,
+		a.CaracteristicasConfort,
+		a.CaracteristicasInterior,
+		a.CaracteristicasEntretenimiento,
+	} {
+		for clave, valor := range caracteristicas {
+			texto = append(texto, clave+" "+valor)
+		}
+	}
+	sort.Strings(texto)
+	a.CaracteristicasTexto = texto
+}
 
-	// Validar estado
-	if a.Estado == "" {
-		a.Estado = EstadoDisponible
-	} else if a.Estado != EstadoDisponible &&
-		a.Estado != EstadoReservado &&
-		a.Estado != EstadoVendido {
-		missingFields = append(missingFields, "estado inválido")
-	}
+// stockIDRegex acepta la letra inicial de la marca seguida de 2 a 9 dígitos, así los
+// stock_id de dos dígitos generados antes siguen siendo válidos
+var stockIDRegex = regexp.MustCompile(`^[A-Z][0-9]{2,9}$`)
 
-	// Validar descuento
-	if a.Descuento < 0 {
-		missingFields = append(missingFields, "descuento no puede ser negativo")
-	}
+// StockIDDigitosDefault es la cantidad de dígitos de los stock_id nuevos si no se
+// configura STOCK_ID_DIGITOS
+const StockIDDigitosDefault = 4
 
-	// Validar que el descuento no supere el precio original
-	if a.Descuento > a.Precio {
-		missingFields = append(missingFields, "descuento no puede ser mayor al precio original")
-	}
-
-	// Validar que el precio con descuento sea positivo
-	if a.Precio-a.Descuento <= 0 {
-		missingFields = append(missingFields, "descuento no puede reducir el precio a cero o negativo")
+func ValidateStockID(stockID string) error {
+	if !stockIDRegex.MatchString(stockID) {
+		return fmt.Errorf("stock_id inválido: debe ser una letra mayúscula seguida de 2 a 9 números")
 	}
+	return nil
+}
 
-	// Validar que haya al menos un equipamiento destacado
-	if len(a.EquipamientoDestacado) == 0 {
-		missingFields = append(missingFields, "equipamiento_destacado")
+// StockIDDigitos devuelve la cantidad mínima de dígitos de los stock_id nuevos,
+// configurable con STOCK_ID_DIGITOS entre 2 y 9
+func StockIDDigitos() int {
+	digitos, err := strconv.Atoi(os.Getenv("STOCK_ID_DIGITOS"))
+	if err != nil || digitos < 2 || digitos > 9 {
+		return StockIDDigitosDefault
 	}
+	return digitos
+}
 
-	if len(missingFields) > 0 {
-		return missingFields, fmt.Errorf("faltan campos requeridos: %v", missingFields)
+// PrefijoStockID devuelve la letra con la que empiezan los stock_id de una marca
+func PrefijoStockID(marca string) (string, error) {
+	marca = strings.ToUpper(strings.TrimSpace(marca))
+	if marca == "" || marca[0] < 'A' || marca[0] > 'Z' {
+		return "", fmt.Errorf("la marca debe empezar con una letra para generar el stock_id")
 	}
-
-	return nil, nil
+	return marca[:1], nil
 }
 
-func ValidateStockID(stockID string) error {
-	pattern := `^[A-Z][0-9]{2}$`
-	matched, _ := regexp.MatchString(pattern, stockID)
-	if !matched {
-		return fmt.Errorf("stock_id inválido: debe ser una letra mayúscula seguida de dos números")
-	}
-	return nil
+// FormatStockID arma el stock_id con el prefijo y el número, completando con ceros
+// hasta la cantidad de dígitos configurada
+func FormatStockID(prefijo string, numero int64) string {
+	return fmt.Sprintf("%s%0*d", prefijo, StockIDDigitos(), numero)
 }
 
 // GenerarIDReserva genera un ID aleatorio para la reserva (formato: letra + 3 números)
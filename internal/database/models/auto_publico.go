@@ -0,0 +1,73 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AutoPublico es la representación de un auto que se expone en las rutas públicas.
+// No incluye reservas ni la información de clientes, talleres o vendedores.
+type AutoPublico struct {
+	StockID                        string              `json:"stock_id" bson:"stock_id"`
+	Marca                          string              `json:"marca" bson:"marca"`
+	Modelo                         string              `json:"modelo" bson:"modelo"`
+	Version                        string              `json:"version" bson:"version"`
+	TipoVenta                      string              `json:"tipo_venta" bson:"tipo_venta"`
+	Año                            int                 `json:"año" bson:"año"`
+	Kilometraje                    int                 `json:"kilometraje" bson:"kilometraje"`
+	Precio                         float64             `json:"precio" bson:"precio"`
+	PrecioLista                    float64             `json:"precio_lista" bson:"precio_lista"`
+	Descuento                      float64             `json:"descuento" bson:"descuento"`
+	PrecioAnterior                 float64             `json:"precio_anterior,omitempty" bson:"precio_anterior,omitempty"`
+	BajoDePrecio                   bool                `json:"bajo_de_precio" bson:"bajo_de_precio"`
+	Moneda                         string              `json:"moneda" bson:"moneda"`
+	PrecioNormalizado              float64             `json:"precio_normalizado" bson:"precio_normalizado"`
+	PreciosDisplay                 *PreciosConvertidos `json:"precios_display,omitempty" bson:"-"`
+	Ciudad                         string              `json:"ciudad" bson:"ciudad"`
+	Sucursal                       string              `json:"sucursal" bson:"sucursal"`
+	Transmision                    string              `json:"transmision" bson:"transmision"`
+	Traccion                       string              `json:"traccion" bson:"traccion"`
+	TipoCombustible                string              `json:"tipo_combustible" bson:"tipo_combustible"`
+	Garantia                       string              `json:"garantia" bson:"garantia"`
+	Featured                       bool                `json:"featured" bson:"featured"`
+	Estado                         string              `json:"estado" bson:"estado"`
+	Imagen_Portada                 string              `json:"imagen_portada" bson:"imagen_portada"`
+	Imagenes                       []string            `json:"imagenes" bson:"imagenes"`
+	Imagenes_Imperfecciones        []string            `json:"imagenes_imperfecciones" bson:"imagenes_imperfecciones"`
+	EquipamientoDestacado          []string            `json:"equipamiento_destacado" bson:"equipamiento_destacado"`
+	CaracteristicasGeneral         map[string]string   `json:"caracteristicas_general" bson:"caracteristicas_general"`
+	CaracteristicasExterior        map[string]string   `json:"caracteristicas_exterior" bson:"caracteristicas_exterior"`
+	CaracteristicasSeguridad       map[string]string   `json:"caracteristicas_seguridad" bson:"caracteristicas_seguridad"`
+	CaracteristicasConfort         map[string]string   `json:"caracteristicas_confort" bson:"caracteristicas_confort"`
+	CaracteristicasInterior        map[string]string   `json:"caracteristicas_interior" bson:"caracteristicas_interior"`
+	CaracteristicasEntretenimiento map[string]string   `json:"caracteristicas_entretenimiento" bson:"caracteristicas_entretenimiento"`
+	CreatedAt                      time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt                      time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// proyeccionPublica se arma una sola vez a partir de los tags bson de AutoPublico,
+// así la proyección y la estructura no pueden desincronizarse
+var proyeccionPublica = func() bson.M {
+	proyeccion := bson.M{"_id": 0}
+	t := reflect.TypeOf(AutoPublico{})
+	for i := 0; i < t.NumField(); i++ {
+		nombre := strings.Split(t.Field(i).Tag.Get("bson"), ",")[0]
+		if nombre != "" && nombre != "-" {
+			proyeccion[nombre] = 1
+		}
+	}
+	return proyeccion
+}()
+
+// ProyeccionPublica devuelve la proyección de MongoDB con los campos públicos de un auto.
+// Se usa en las rutas públicas para que los campos privados nunca se lean de la base.
+func ProyeccionPublica() bson.M {
+	proyeccion := make(bson.M, len(proyeccionPublica))
+	for campo, valor := range proyeccionPublica {
+		proyeccion[campo] = valor
+	}
+	return proyeccion
+}
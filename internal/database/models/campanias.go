@@ -0,0 +1,118 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Reglas de precedencia entre el descuento de una campaña y el descuento individual
+const (
+	// PrecedenciaMejorPrecio aplica el descuento más alto de los dos
+	PrecedenciaMejorPrecio = "mejor_precio"
+	// PrecedenciaCampania aplica el descuento de la campaña si está vigente
+	PrecedenciaCampania = "campania"
+	// PrecedenciaIndividual aplica el descuento individual si está vigente
+	PrecedenciaIndividual = "individual"
+)
+
+// Campania es una promoción que se aplica a todos los autos que cumplen un filtro.
+// El filtro usa los mismos parámetros que el catálogo público (GET /api/autos).
+type Campania struct {
+	ID          string            `json:"id" bson:"id"`
+	Nombre      string            `json:"nombre" bson:"nombre"`
+	Filtro      map[string]string `json:"filtro" bson:"filtro"`
+	Promocion   Promocion         `json:"promocion" bson:"promocion"`
+	Precedencia string            `json:"precedencia" bson:"precedencia"`
+	StockIDs    []string          `json:"stock_ids" bson:"stock_ids"`
+	CreadoEn    time.Time         `json:"creado_en" bson:"creado_en"`
+}
+
+// PromocionCampania es la copia de la promoción de una campaña guardada en cada auto
+// alcanzado, para poder calcular el precio efectivo al leer
+type PromocionCampania struct {
+	Promocion   `bson:",inline"`
+	CampaniaID  string `json:"campania_id" bson:"campania_id"`
+	Nombre      string `json:"nombre" bson:"nombre"`
+	Precedencia string `json:"precedencia" bson:"precedencia"`
+}
+
+// Validar verifica los datos de la campaña. La promoción se valida contra cada auto al aplicarla.
+func (c *Campania) Validar() error {
+	if c.Nombre == "" {
+		return errors.New("la campaña requiere un nombre")
+	}
+	if err := c.Promocion.Validar(math.MaxFloat64); err != nil {
+		return err
+	}
+	if c.Promocion.Tipo == DescuentoMonto && c.Promocion.Moneda == "" {
+		return errors.New("las campañas por monto requieren la moneda del descuento")
+	}
+	if c.Promocion.ValidoHasta == nil {
+		return errors.New("la campaña requiere valido_hasta para vencer automáticamente")
+	}
+	switch c.Precedencia {
+	case "":
+		c.Precedencia = PrecedenciaMejorPrecio
+	case PrecedenciaMejorPrecio, PrecedenciaCampania, PrecedenciaIndividual:
+	default:
+		return errors.New("precedencia inválida: debe ser mejor_precio, campania o individual")
+	}
+	return nil
+}
+
+// Activa indica si la campaña está vigente en el momento indicado
+func (c *Campania) Activa(now time.Time) bool {
+	return c.Promocion.Activa(now)
+}
+
+// PromocionParaAutos devuelve la promoción que se guarda en los autos alcanzados
+func (c *Campania) PromocionParaAutos() PromocionCampania {
+	return PromocionCampania{
+		Promocion:   c.Promocion,
+		CampaniaID:  c.ID,
+		Nombre:      c.Nombre,
+		Precedencia: c.Precedencia,
+	}
+}
+
+// Combinar decide qué descuento se aplica según la precedencia de la campaña. Los montos
+// son 0 cuando la promoción correspondiente no está vigente.
+func (p *PromocionCampania) Combinar(individual float64, campania float64) float64 {
+	switch p.Precedencia {
+	case PrecedenciaCampania:
+		if campania > 0 {
+			return campania
+		}
+		return individual
+	case PrecedenciaIndividual:
+		if individual > 0 {
+			return individual
+		}
+		return campania
+	default:
+		return max(individual, campania)
+	}
+}
+
+// exprCombinarDescuentos arma la expresión de agregación equivalente a Combinar
+func exprCombinarDescuentos(individual bson.M, campania bson.M) bson.M {
+	return bson.M{"$let": bson.M{
+		"vars": bson.M{"individual": individual, "campania": campania},
+		"in": bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{
+					"case": bson.M{"$eq": bson.A{"$campania.precedencia", PrecedenciaCampania}},
+					"then": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$$campania", 0}}, "$$campania", "$$individual"}},
+				},
+				bson.M{
+					"case": bson.M{"$eq": bson.A{"$campania.precedencia", PrecedenciaIndividual}},
+					"then": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$$individual", 0}}, "$$individual", "$$campania"}},
+				},
+			},
+			"default": bson.M{"$max": bson.A{"$$individual", "$$campania"}},
+		}},
+	}}
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Tipos de descuento
+const (
+	DescuentoPorcentaje = "porcentaje"
+	DescuentoMonto      = "monto"
+)
+
+// Promocion es un descuento sobre el precio de lista de un auto. Puede tener una
+// ventana de vigencia [valido_desde, valido_hasta); fuera de ella no se aplica. Los
+// descuentos por monto están en una moneda y solo se aplican a autos en esa moneda.
+type Promocion struct {
+	Tipo        string     `json:"tipo" bson:"tipo"`
+	Valor       float64    `json:"valor" bson:"valor"`
+	Moneda      string     `json:"moneda,omitempty" bson:"moneda,omitempty"`
+	ValidoDesde *time.Time `json:"valido_desde,omitempty" bson:"valido_desde,omitempty"`
+	ValidoHasta *time.Time `json:"valido_hasta,omitempty" bson:"valido_hasta,omitempty"`
+	CreadoEn    time.Time  `json:"creado_en" bson:"creado_en"`
+}
+
+// Validar verifica que la promoción sea coherente con el precio de lista
+func (p *Promocion) Validar(precioLista float64) error {
+	switch p.Tipo {
+	case DescuentoPorcentaje:
+		if p.Valor <= 0 || p.Valor >= 100 {
+			return errors.New("el porcentaje de descuento debe ser mayor a 0 y menor a 100")
+		}
+		// Un porcentaje vale para cualquier moneda
+		p.Moneda = ""
+	case DescuentoMonto:
+		p.Moneda = NormalizarMoneda(p.Moneda)
+		if p.Moneda != "" && !monedaRegex.MatchString(p.Moneda) {
+			return errors.New("la moneda del descuento debe ser un código de tres letras, por ejemplo ARS")
+		}
+		if p.Valor <= 0 {
+			return errors.New("el descuento debe ser mayor a cero")
+		}
+		if p.Valor >= precioLista {
+			return errors.New("el descuento no puede reducir el precio a cero o negativo")
+		}
+	default:
+		return errors.New("tipo de descuento inválido: debe ser porcentaje o monto")
+	}
+
+	if p.ValidoDesde != nil && p.ValidoHasta != nil && !p.ValidoHasta.After(*p.ValidoDesde) {
+		return errors.New("valido_hasta debe ser posterior a valido_desde")
+	}
+	return nil
+}
+
+// ValidarMoneda verifica que un descuento por monto esté en la moneda del auto. Los
+// montos no se convierten entre monedas; sin moneda se toman en la moneda del auto.
+func (p *Promocion) ValidarMoneda(moneda string) error {
+	if p.Tipo != DescuentoMonto || p.Moneda == "" {
+		return nil
+	}
+	if moneda = NormalizarMoneda(moneda); moneda != p.Moneda {
+		return fmt.Errorf("el descuento es un monto en %s y el auto está en %s", p.Moneda, moneda)
+	}
+	return nil
+}
+
+// Activa indica si la promoción está vigente en el momento indicado
+func (p *Promocion) Activa(now time.Time) bool {
+	if p == nil {
+		return false
+	}
+	if p.ValidoDesde != nil && now.Before(*p.ValidoDesde) {
+		return false
+	}
+	if p.ValidoHasta != nil && !now.Before(*p.ValidoHasta) {
+		return false
+	}
+	return true
+}
+
+// Monto devuelve cuánto descuenta la promoción sobre el precio de lista
+func (p *Promocion) Monto(precioLista float64) float64 {
+	if p.Tipo == DescuentoPorcentaje {
+		return math.RoundToEven(precioLista*p.Valor) / 100
+	}
+	return p.Valor
+}
+
+// PrecioDeLista devuelve el precio de lista, usando el precio para autos cargados
+// antes de que existiera precio_lista
+func (a *Auto) PrecioDeLista() float64 {
+	if a.PrecioLista > 0 {
+		return a.PrecioLista
+	}
+	return a.Precio
+}
+
+// Inicio devuelve desde cuándo rige la promoción: valido_desde o, si no tiene ventana,
+// el momento en que se creó
+func (p *Promocion) Inicio() time.Time {
+	if p.ValidoDesde != nil {
+		return *p.ValidoDesde
+	}
+	return p.CreadoEn
+}
+
+// AplicarPrecioEfectivo calcula precio y descuento a partir del precio de lista, la
+// promoción individual y la de campaña vigentes en el momento indicado. El precio
+// guardado es el efectivo de la última escritura: si una ventana de descuento se abrió
+// desde entonces el auto bajó de precio al abrirse, y si se cerró ya no está bajo de precio.
+func (a *Auto) AplicarPrecioEfectivo(now time.Time) {
+	lista := a.PrecioDeLista()
+	individual := 0.0
+	if a.Promocion.Activa(now) {
+		individual = a.Promocion.Monto(lista)
+	}
+	campania := 0.0
+	if a.Campania != nil && a.Campania.Promocion.Activa(now) {
+		campania = a.Campania.Promocion.Monto(lista)
+	}
+
+	descuento := individual
+	if a.Campania != nil {
+		descuento = a.Campania.Combinar(individual, campania)
+	}
+
+	guardado := a.Precio
+	a.PrecioLista = lista
+	a.Descuento = descuento
+	a.Precio = lista - descuento
+
+	switch {
+	case a.Precio < guardado:
+		a.PrecioAnterior = guardado
+		a.BajoDePrecio = true
+		a.BajoDePrecioEn = a.UpdatedAt
+		if a.Promocion.Activa(now) && a.Promocion.Inicio().After(a.BajoDePrecioEn) {
+			a.BajoDePrecioEn = a.Promocion.Inicio()
+		}
+		if a.Campania != nil && a.Campania.Promocion.Activa(now) && a.Campania.Promocion.Inicio().After(a.BajoDePrecioEn) {
+			a.BajoDePrecioEn = a.Campania.Promocion.Inicio()
+		}
+	case a.Precio > guardado:
+		a.PrecioAnterior = 0
+		a.BajoDePrecio = false
+		a.BajoDePrecioEn = time.Time{}
+	}
+}
+
+// EtapasPrecioEfectivo devuelve las etapas de agregación que calculan al leer el
+// descuento vigente, el precio efectivo y los indicadores de baja de precio, con la
+// misma lógica que AplicarPrecioEfectivo
+func EtapasPrecioEfectivo() []bson.D {
+	lista := bson.M{"$ifNull": bson.A{"$precio_lista", "$precio"}}
+	individual := exprMontoPromocion("$promocion", lista)
+	campania := exprMontoPromocion("$campania", lista)
+
+	// En la segunda etapa $precio todavía es el precio guardado en la última escritura
+	precio := bson.M{"$subtract": bson.A{"$precio_lista", "$descuento"}}
+	bajo := bson.M{"$lt": bson.A{precio, "$precio"}}
+	subio := bson.M{"$gt": bson.A{precio, "$precio"}}
+	inicio := bson.M{"$max": bson.A{
+		"$updated_at",
+		bson.M{"$cond": bson.A{exprPromocionActiva("$promocion"), exprInicioPromocion("$promocion"), nil}},
+		bson.M{"$cond": bson.A{exprPromocionActiva("$campania"), exprInicioPromocion("$campania"), nil}},
+	}}
+
+	return []bson.D{
+		{{Key: "$addFields", Value: bson.D{
+			{Key: "precio_lista", Value: lista},
+			{Key: "descuento", Value: exprCombinarDescuentos(individual, campania)},
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"precio":            precio,
+			"precio_anterior":   bson.M{"$switch": bson.M{"branches": bson.A{bson.M{"case": bajo, "then": "$precio"}, bson.M{"case": subio, "then": "$$REMOVE"}}, "default": "$precio_anterior"}},
+			"bajo_de_precio":    bson.M{"$switch": bson.M{"branches": bson.A{bson.M{"case": bajo, "then": true}, bson.M{"case": subio, "then": false}}, "default": bson.M{"$ifNull": bson.A{"$bajo_de_precio", false}}}},
+			"bajo_de_precio_en": bson.M{"$switch": bson.M{"branches": bson.A{bson.M{"case": bajo, "then": inicio}, bson.M{"case": subio, "then": "$$REMOVE"}}, "default": "$bajo_de_precio_en"}},
+		}}},
+	}
+}
+
+// exprPromocionActiva arma la expresión que indica si la promoción guardada en campo
+// existe y está vigente, igual que Promocion.Activa
+func exprPromocionActiva(campo string) bson.M {
+	return bson.M{"$and": bson.A{
+		bson.M{"$ne": bson.A{bson.M{"$ifNull": bson.A{campo, nil}}, nil}},
+		bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{campo + ".valido_desde", "$$NOW"}}, "$$NOW"}},
+		bson.M{"$or": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$ifNull": bson.A{campo + ".valido_hasta", nil}}, nil}},
+			bson.M{"$gt": bson.A{campo + ".valido_hasta", "$$NOW"}},
+		}},
+	}}
+}
+
+// exprInicioPromocion arma la expresión de desde cuándo rige la promoción guardada en
+// campo, igual que Promocion.Inicio
+func exprInicioPromocion(campo string) bson.M {
+	return bson.M{"$ifNull": bson.A{campo + ".valido_desde", campo + ".creado_en"}}
+}
+
+// exprMontoPromocion arma la expresión del monto que descuenta la promoción guardada en
+// campo, o 0 si no existe o no está vigente
+func exprMontoPromocion(campo string, lista interface{}) bson.M {
+	activa := exprPromocionActiva(campo)
+
+	// $round redondea al par más cercano, igual que math.RoundToEven en Monto
+	monto := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{campo + ".tipo", DescuentoPorcentaje}},
+		bson.M{"$divide": bson.A{bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{lista, campo + ".valor"}}, 0}}, 100}},
+		campo + ".valor",
+	}}
+
+	return bson.M{"$cond": bson.A{activa, monto, 0}}
+}
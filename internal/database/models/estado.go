@@ -0,0 +1,146 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Estados adicionales del ciclo de vida de un auto
+const (
+	EstadoEnNegociacion   = "en negociación"
+	EstadoEnMantenimiento = "en mantenimiento"
+)
+
+// EstadosValidos son todos los estados posibles de un auto
+var EstadosValidos = []string{
+	EstadoDisponible,
+	EstadoEnNegociacion,
+	EstadoReservado,
+	EstadoVendido,
+	EstadoEnMantenimiento,
+}
+
+// transicionesPermitidas define la máquina de estados del auto:
+// disponible → en negociación → reservado → vendido, y disponible ↔ en mantenimiento.
+// Desde vendido no hay transiciones sin forzar el cambio.
+var transicionesPermitidas = map[string][]string{
+	EstadoDisponible:      {EstadoEnNegociacion, EstadoEnMantenimiento},
+	EstadoEnNegociacion:   {EstadoReservado},
+	EstadoReservado:       {EstadoVendido},
+	EstadoEnMantenimiento: {EstadoDisponible},
+	EstadoVendido:         {},
+}
+
+// ErrTransicionInvalida indica que la máquina de estados no permite el cambio pedido
+var ErrTransicionInvalida = errors.New("transición de estado no permitida")
+
+// TransicionEstado registra un cambio de estado de un auto
+type TransicionEstado struct {
+	Desde   string    `json:"desde" bson:"desde"`
+	Hacia   string    `json:"hacia" bson:"hacia"`
+	Fecha   time.Time `json:"fecha" bson:"fecha"`
+	Forzada bool      `json:"forzada" bson:"forzada"`
+}
+
+// InfoEstado agrupa la información que acompaña a cada estado
+type InfoEstado struct {
+	ReservadoPor    *ReservadoInfo
+	VendidoPor      *VendidoInfo
+	EnNegociacion   *NegociacionInfo
+	EnMantenimiento *MantenimientoInfo
+}
+
+// EsEstadoValido indica si el estado es uno de los estados definidos
+func EsEstadoValido(estado string) bool {
+	for _, valido := range EstadosValidos {
+		if estado == valido {
+			return true
+		}
+	}
+	return false
+}
+
+// EstadoActual devuelve el estado del auto, considerando disponible a los autos sin estado
+func (a *Auto) EstadoActual() string {
+	if a.Estado == "" {
+		return EstadoDisponible
+	}
+	return a.Estado
+}
+
+// ValidarTransicion verifica que se pueda pasar de un estado a otro.
+// Con forzar, un administrador puede saltear la máquina de estados.
+func ValidarTransicion(desde string, hacia string, forzar bool) error {
+	if !EsEstadoValido(hacia) {
+		return fmt.Errorf("estado inválido: %s", hacia)
+	}
+	if desde == hacia {
+		return fmt.Errorf("%w: el auto ya está %s", ErrTransicionInvalida, hacia)
+	}
+	if forzar {
+		return nil
+	}
+	for _, permitido := range transicionesPermitidas[desde] {
+		if permitido == hacia {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: de %s a %s", ErrTransicionInvalida, desde, hacia)
+}
+
+// Validar verifica que esté presente la información que requiere el estado
+func (i InfoEstado) Validar(estado string) error {
+	switch estado {
+	case EstadoReservado:
+		if i.ReservadoPor == nil {
+			return errors.New("el estado reservado requiere reservado_por")
+		}
+	case EstadoVendido:
+		if i.VendidoPor == nil {
+			return errors.New("el estado vendido requiere vendido_por")
+		}
+	case EstadoEnNegociacion:
+		if i.EnNegociacion == nil {
+			return errors.New("el estado en negociación requiere en_negociacion")
+		}
+	case EstadoEnMantenimiento:
+		if i.EnMantenimiento == nil {
+			return errors.New("el estado en mantenimiento requiere en_mantenimiento")
+		}
+	}
+	return nil
+}
+
+// CampoInfoEstado devuelve el campo de la base que guarda la información del estado,
+// o "" si el estado no tiene información asociada
+func CampoInfoEstado(estado string) string {
+	switch estado {
+	case EstadoReservado:
+		return "reservado_por"
+	case EstadoVendido:
+		return "vendido_por"
+	case EstadoEnNegociacion:
+		return "en_negociacion"
+	case EstadoEnMantenimiento:
+		return "en_mantenimiento"
+	default:
+		return ""
+	}
+}
+
+// Valor devuelve la información correspondiente al estado indicado
+func (i InfoEstado) Valor(estado string) interface{} {
+	switch estado {
+	case EstadoReservado:
+		return i.ReservadoPor
+	case EstadoVendido:
+		return i.VendidoPor
+	case EstadoEnNegociacion:
+		return i.EnNegociacion
+	case EstadoEnMantenimiento:
+		return i.EnMantenimiento
+	default:
+		return nil
+	}
+}
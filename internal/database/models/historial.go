@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// camposSinHistorial son los campos que cambian en cada escritura o que son derivados,
+// y por eso no se registran en el historial
+var camposSinHistorial = map[string]bool{
+	"_id":                   true,
+	"updated_at":            true,
+	"revision":              true,
+	"caracteristicas_texto": true,
+	"historial_estados":     true,
+	"historial_precios":     true,
+}
+
+// CambioCampo es el cambio de un campo de un auto
+type CambioCampo struct {
+	Campo    string      `json:"campo" bson:"campo"`
+	Anterior interface{} `json:"anterior" bson:"anterior"`
+	Nuevo    interface{} `json:"nuevo" bson:"nuevo"`
+}
+
+// RegistroHistorial es una entrada del historial de cambios de un auto
+type RegistroHistorial struct {
+	StockID  string        `json:"stock_id" bson:"stock_id"`
+	Fecha    time.Time     `json:"fecha" bson:"fecha"`
+	Usuario  string        `json:"usuario" bson:"usuario"`
+	Metodo   string        `json:"metodo" bson:"metodo"`
+	Endpoint string        `json:"endpoint" bson:"endpoint"`
+	Cambios  []CambioCampo `json:"cambios" bson:"cambios"`
+}
+
+// DiffAutos compara dos versiones de un auto y devuelve los campos que cambiaron,
+// ordenados por nombre de campo
+func DiffAutos(antes Auto, despues Auto) ([]CambioCampo, error) {
+	docAntes, err := toBSONMap(antes)
+	if err != nil {
+		return nil, err
+	}
+	docDespues, err := toBSONMap(despues)
+	if err != nil {
+		return nil, err
+	}
+
+	campos := map[string]bool{}
+	for campo := range docAntes {
+		campos[campo] = true
+	}
+	for campo := range docDespues {
+		campos[campo] = true
+	}
+
+	cambios := []CambioCampo{}
+	for campo := range campos {
+		if camposSinHistorial[campo] {
+			continue
+		}
+		if !reflect.DeepEqual(docAntes[campo], docDespues[campo]) {
+			cambios = append(cambios, CambioCampo{
+				Campo:    campo,
+				Anterior: docAntes[campo],
+				Nuevo:    docDespues[campo],
+			})
+		}
+	}
+
+	sort.Slice(cambios, func(i, j int) bool { return cambios[i].Campo < cambios[j].Campo })
+	return cambios, nil
+}
+
+// toBSONMap convierte un valor a su representación BSON como mapa
+func toBSONMap(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
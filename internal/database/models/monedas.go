@@ -0,0 +1,141 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MonedaReferencia es la moneda a la que se normalizan los precios para filtrar y ordenar
+const MonedaReferencia = "USD"
+
+var monedaRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// TipoCambio es la cotización de una moneda: cuántas unidades de la moneda equivalen a
+// una unidad de la moneda de referencia (por ejemplo ARS 1000 significa 1 USD = 1000 ARS)
+type TipoCambio struct {
+	Moneda        string    `json:"moneda" bson:"moneda"`
+	Tasa          float64   `json:"tasa" bson:"tasa"`
+	ActualizadoEn time.Time `json:"actualizado_en" bson:"actualizado_en"`
+	Usuario       string    `json:"usuario" bson:"usuario"`
+}
+
+// Validar verifica la moneda y la tasa del tipo de cambio
+func (t *TipoCambio) Validar() error {
+	t.Moneda = NormalizarMoneda(t.Moneda)
+	if !monedaRegex.MatchString(t.Moneda) {
+		return errors.New("la moneda debe ser un código de tres letras, por ejemplo ARS")
+	}
+	if t.Moneda == MonedaReferencia {
+		return errors.New("la moneda de referencia " + MonedaReferencia + " no tiene tipo de cambio")
+	}
+	if t.Tasa <= 0 || math.IsInf(t.Tasa, 0) || math.IsNaN(t.Tasa) {
+		return errors.New("la tasa debe ser mayor a cero")
+	}
+	return nil
+}
+
+// NormalizarMoneda lleva el código de moneda al formato guardado en la tabla de cambios
+func NormalizarMoneda(moneda string) string {
+	return strings.ToUpper(strings.TrimSpace(moneda))
+}
+
+// TiposCambio son las tasas vigentes por moneda
+type TiposCambio map[string]float64
+
+// Tasa devuelve la tasa de la moneda; la moneda de referencia siempre vale 1
+func (t TiposCambio) Tasa(moneda string) (float64, bool) {
+	moneda = NormalizarMoneda(moneda)
+	if moneda == MonedaReferencia {
+		return 1, true
+	}
+	tasa, ok := t[moneda]
+	return tasa, ok
+}
+
+// Monedas devuelve las monedas en las que se puede cargar un auto: la de referencia y
+// las que tienen tipo de cambio, para poder normalizar sus precios
+func (t TiposCambio) Monedas() []string {
+	monedas := make([]string, 0, len(t)+1)
+	for moneda := range t {
+		if moneda != MonedaReferencia {
+			monedas = append(monedas, moneda)
+		}
+	}
+	sort.Strings(monedas)
+	return append([]string{MonedaReferencia}, monedas...)
+}
+
+// Convertir convierte un monto entre dos monedas pasando por la moneda de referencia,
+// redondeado a dos decimales. Devuelve false si falta alguna de las tasas.
+func (t TiposCambio) Convertir(monto float64, desde string, hacia string) (float64, bool) {
+	tasaDesde, ok := t.Tasa(desde)
+	if !ok {
+		return 0, false
+	}
+	tasaHacia, ok := t.Tasa(hacia)
+	if !ok {
+		return 0, false
+	}
+	return math.Round(monto/tasaDesde*tasaHacia*100) / 100, true
+}
+
+// PreciosConvertidos son los precios de un auto expresados en otra moneda
+type PreciosConvertidos struct {
+	Moneda         string  `json:"moneda"`
+	Precio         float64 `json:"precio"`
+	PrecioLista    float64 `json:"precio_lista"`
+	Descuento      float64 `json:"descuento"`
+	PrecioAnterior float64 `json:"precio_anterior,omitempty"`
+}
+
+// ConvertirPrecios agrega los precios del auto en la moneda indicada. Si falta el tipo
+// de cambio de la moneda del auto deja los precios sin convertir.
+func (a *AutoPublico) ConvertirPrecios(tipos TiposCambio, moneda string) {
+	precio, ok := tipos.Convertir(a.Precio, a.Moneda, moneda)
+	if !ok {
+		return
+	}
+	lista, _ := tipos.Convertir(a.PrecioLista, a.Moneda, moneda)
+	descuento, _ := tipos.Convertir(a.Descuento, a.Moneda, moneda)
+	anterior, _ := tipos.Convertir(a.PrecioAnterior, a.Moneda, moneda)
+	a.PreciosDisplay = &PreciosConvertidos{
+		Moneda:         NormalizarMoneda(moneda),
+		Precio:         precio,
+		PrecioLista:    lista,
+		Descuento:      descuento,
+		PrecioAnterior: anterior,
+	}
+}
+
+// EtapasPrecioNormalizado devuelve las etapas de agregación que calculan
+// precio_normalizado, el precio efectivo en la moneda de referencia. Deben ir después
+// de EtapasPrecioEfectivo. Si la moneda no tiene tipo de cambio se toma el precio sin
+// convertir para no sacar el auto del catálogo.
+func EtapasPrecioNormalizado() []bson.D {
+	moneda := bson.M{"$toUpper": bson.M{"$ifNull": bson.A{"$moneda", ""}}}
+	return []bson.D{
+		{{Key: "$lookup", Value: bson.M{
+			"from":     "tipos_cambio",
+			"let":      bson.M{"moneda": moneda},
+			"pipeline": bson.A{bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$moneda", "$$moneda"}}}}},
+			"as":       "tipo_cambio",
+		}}},
+		{{Key: "$addFields", Value: bson.M{
+			"precio_normalizado": bson.M{"$cond": bson.A{
+				bson.M{"$eq": bson.A{moneda, MonedaReferencia}},
+				"$precio",
+				bson.M{"$divide": bson.A{
+					"$precio",
+					bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$tipo_cambio.tasa", 0}}, 1}},
+				}},
+			}},
+		}}},
+		{{Key: "$unset", Value: "tipo_cambio"}},
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Motivos de un cambio de precio
+const (
+	MotivoPrecioAlta          = "alta"
+	MotivoPrecioActualizacion = "actualizacion"
+	MotivoPrecioDescuento     = "descuento"
+	MotivoPrecioSinDescuento  = "descuento eliminado"
+	MotivoPrecioCampania      = "campaña"
+	MotivoPrecioSinCampania   = "campaña eliminada"
+
+	// Motivos de los puntos que SeriePrecios agrega al abrirse o cerrarse una ventana de
+	// vigencia; no se guardan en la serie
+	MotivoPrecioInicioDescuento = "inicio de descuento"
+	MotivoPrecioFinDescuento    = "fin de descuento"
+	MotivoPrecioInicioCampania  = "inicio de campaña"
+	MotivoPrecioFinCampania     = "fin de campaña"
+)
+
+// PrecioHistorico es un punto de la serie de precios de un auto
+type PrecioHistorico struct {
+	Precio float64   `json:"precio" bson:"precio"`
+	Fecha  time.Time `json:"fecha" bson:"fecha"`
+	Motivo string    `json:"motivo" bson:"motivo"`
+}
+
+// IniciarHistorialPrecios registra el precio con el que se da de alta el auto
+func (a *Auto) IniciarHistorialPrecios(fecha time.Time) {
+	a.HistorialPrecios = []PrecioHistorico{{Precio: a.Precio, Fecha: fecha, Motivo: MotivoPrecioAlta}}
+	a.PrecioAnterior = 0
+	a.BajoDePrecio = false
+	a.BajoDePrecioEn = time.Time{}
+}
+
+// CambioPrecio cambia el precio del auto, lo agrega a la serie histórica y actualiza
+// los indicadores de baja de precio
+func (a *Auto) CambioPrecio(nuevo float64, motivo string, fecha time.Time) {
+	// Los autos cargados antes de guardar la serie arrancan con su precio actual
+	if len(a.HistorialPrecios) == 0 {
+		a.HistorialPrecios = append(a.HistorialPrecios, PrecioHistorico{Precio: a.Precio, Fecha: a.CreatedAt, Motivo: MotivoPrecioAlta})
+	}
+	a.HistorialPrecios = append(a.HistorialPrecios, PrecioHistorico{Precio: nuevo, Fecha: fecha, Motivo: motivo})
+
+	anterior := a.Precio
+	a.Precio = nuevo
+	if nuevo < anterior {
+		a.PrecioAnterior = anterior
+		a.BajoDePrecio = true
+		a.BajoDePrecioEn = fecha
+	} else {
+		a.PrecioAnterior = 0
+		a.BajoDePrecio = false
+		a.BajoDePrecioEn = time.Time{}
+	}
+}
+
+// CambiarCampania asigna la campaña al auto, o la quita si es nil. Si eso cambia el precio
+// efectivo en la fecha indicada, agrega el punto a la serie de precios.
+func (a *Auto) CambiarCampania(campania *PromocionCampania, fecha time.Time) {
+	a.AplicarPrecioEfectivo(fecha)
+	anterior := a.Precio
+	a.Campania = campania
+	nuevo := *a
+	nuevo.AplicarPrecioEfectivo(fecha)
+
+	if nuevo.Precio != anterior {
+		motivo := MotivoPrecioCampania
+		if campania == nil {
+			motivo = MotivoPrecioSinCampania
+		}
+		a.CambioPrecio(nuevo.Precio, motivo, fecha)
+	}
+	a.Descuento = nuevo.Descuento
+	a.UpdatedAt = fecha
+}
+
+// limiteVigencia es la apertura o el cierre de la ventana de una promoción
+type limiteVigencia struct {
+	fecha  time.Time
+	motivo string
+}
+
+// SeriePrecios devuelve la serie de precios para graficar hasta el momento indicado.
+// Abrir o cerrar la ventana de vigencia de un descuento o de una campaña cambia el precio
+// efectivo sin escribir el auto, así que no queda en la serie guardada. Se agrega un punto
+// por cada límite posterior al último punto guardado que cambia el precio, para que el
+// gráfico termine en el mismo precio que informa bajo_de_precio. Los límites anteriores
+// ya quedaron reflejados en el precio que registró la escritura siguiente.
+func (a *Auto) SeriePrecios(now time.Time) []PrecioHistorico {
+	// Los autos cargados antes de guardar la serie muestran solo su precio actual
+	serie := append([]PrecioHistorico(nil), a.HistorialPrecios...)
+	if len(serie) == 0 {
+		serie = []PrecioHistorico{{Precio: a.Precio, Fecha: a.CreatedAt, Motivo: MotivoPrecioAlta}}
+	}
+	ultimo := serie[len(serie)-1]
+
+	var limites []limiteVigencia
+	agregar := func(fecha *time.Time, motivo string) {
+		if fecha != nil && fecha.After(ultimo.Fecha) && !fecha.After(now) {
+			limites = append(limites, limiteVigencia{fecha: *fecha, motivo: motivo})
+		}
+	}
+	if a.Promocion != nil {
+		agregar(a.Promocion.ValidoDesde, MotivoPrecioInicioDescuento)
+		agregar(a.Promocion.ValidoHasta, MotivoPrecioFinDescuento)
+	}
+	if a.Campania != nil {
+		agregar(a.Campania.ValidoDesde, MotivoPrecioInicioCampania)
+		agregar(a.Campania.ValidoHasta, MotivoPrecioFinCampania)
+	}
+	sort.SliceStable(limites, func(i, j int) bool { return limites[i].fecha.Before(limites[j].fecha) })
+
+	precio := ultimo.Precio
+	for _, limite := range limites {
+		enLimite := *a
+		enLimite.AplicarPrecioEfectivo(limite.fecha)
+		if enLimite.Precio != precio {
+			serie = append(serie, PrecioHistorico{Precio: enLimite.Precio, Fecha: limite.fecha, Motivo: limite.motivo})
+			precio = enLimite.Precio
+		}
+	}
+	return serie
+}
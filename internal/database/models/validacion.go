@@ -0,0 +1,220 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Reglas de validación que se informan en cada ErrorCampo
+const (
+	ReglaRequerido   = "required"
+	ReglaMin         = "min"
+	ReglaMax         = "max"
+	ReglaEnum        = "enum"
+	ReglaFormato     = "format"
+	ReglaSoloLectura = "read_only"
+)
+
+// Límites de los campos numéricos del auto
+const (
+	AñoMinimo         = 1950
+	KilometrajeMaximo = 1000000
+)
+
+// Valores aceptados para los campos con lista cerrada. Al validar se comparan sin
+// distinguir mayúsculas ni acentos y se guardan con la forma de la lista.
+var (
+	Transmisiones    = []string{"manual", "automática", "automatizada", "cvt"}
+	Tracciones       = []string{"4x2", "4x4", "awd", "delantera", "trasera"}
+	TiposCombustible = []string{"nafta", "diésel", "gnc", "nafta/gnc", "híbrido", "eléctrico"}
+)
+
+// CamposProtegidos son los campos del auto que administra el servidor: se calculan o
+// cambian solo a través de sus propios endpoints, así que un PATCH no puede escribirlos
+var CamposProtegidos = map[string]bool{
+	"stock_id":              true,
+	"created_at":            true,
+	"updated_at":            true,
+	"revision":              true,
+	"deleted_at":            true,
+	"reservas":              true,
+	"estado":                true,
+	"estado_actualizado_en": true,
+	"historial_estados":     true,
+	"reservado_por":         true,
+	"vendido_por":           true,
+	"en_negociacion":        true,
+	"en_mantenimiento":      true,
+	"descuento":             true,
+	"promocion":             true,
+	"campania":              true,
+	"precio_anterior":       true,
+	"bajo_de_precio":        true,
+	"bajo_de_precio_en":     true,
+	"historial_precios":     true,
+}
+
+// ValidarCamposEditables devuelve un error read_only por cada campo protegido de la
+// lista. El campo "" representa el documento entero.
+func ValidarCamposEditables(campos []string) ErroresValidacion {
+	v := &validador{}
+	vistos := map[string]bool{}
+	for _, campo := range campos {
+		if vistos[campo] {
+			continue
+		}
+		vistos[campo] = true
+		if campo == "" {
+			v.agregar(campo, ReglaSoloLectura, "no se puede reemplazar el auto completo", nil)
+		} else if CamposProtegidos[campo] {
+			v.agregar(campo, ReglaSoloLectura, "lo administra el servidor y no se puede modificar", nil)
+		}
+	}
+	return v.errores
+}
+
+// ErrorCampo describe por qué un campo no es válido. Parametro es el límite de las
+// reglas min y max o la lista de valores de la regla enum.
+type ErrorCampo struct {
+	Campo     string      `json:"campo"`
+	Regla     string      `json:"regla"`
+	Mensaje   string      `json:"mensaje"`
+	Parametro interface{} `json:"parametro,omitempty"`
+}
+
+// ErroresValidacion son los errores de todos los campos inválidos
+type ErroresValidacion []ErrorCampo
+
+func (e ErroresValidacion) Error() string {
+	partes := make([]string, len(e))
+	for i, campo := range e {
+		partes[i] = campo.Campo + ": " + campo.Mensaje
+	}
+	return "datos inválidos: " + strings.Join(partes, "; ")
+}
+
+// validador acumula los errores de los campos en el orden en que se validan
+type validador struct {
+	errores ErroresValidacion
+}
+
+func (v *validador) agregar(campo string, regla string, mensaje string, parametro interface{}) {
+	v.errores = append(v.errores, ErrorCampo{Campo: campo, Regla: regla, Mensaje: mensaje, Parametro: parametro})
+}
+
+func (v *validador) requerido(campo string, valor string) bool {
+	if strings.TrimSpace(valor) == "" {
+		v.agregar(campo, ReglaRequerido, "es obligatorio", nil)
+		return false
+	}
+	return true
+}
+
+func (v *validador) rango(campo string, valor int, min int, max int) {
+	if valor < min {
+		v.agregar(campo, ReglaMin, fmt.Sprintf("debe ser al menos %d", min), min)
+	} else if valor > max {
+		v.agregar(campo, ReglaMax, fmt.Sprintf("no puede superar %d", max), max)
+	}
+}
+
+// enum valida que el valor esté en la lista y lo reemplaza por la forma de la lista
+func (v *validador) enum(campo string, valor *string, validos []string) {
+	if !v.requerido(campo, *valor) {
+		return
+	}
+	buscado := normalizarEnum(*valor)
+	for _, valido := range validos {
+		if normalizarEnum(valido) == buscado {
+			*valor = valido
+			return
+		}
+	}
+	v.agregar(campo, ReglaEnum, "debe ser uno de: "+strings.Join(validos, ", "), validos)
+}
+
+func (v *validador) urls(campo string, valores []string) {
+	for i, valor := range valores {
+		v.url(fmt.Sprintf("%s[%d]", campo, i), valor)
+	}
+}
+
+func (v *validador) url(campo string, valor string) {
+	u, err := url.Parse(valor)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.agregar(campo, ReglaFormato, "debe ser una URL http o https", "url")
+	}
+}
+
+// sinAcentos quita los acentos para comparar valores escritos con o sin ellos
+var sinAcentos = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u")
+
+func normalizarEnum(valor string) string {
+	return sinAcentos.Replace(strings.ToLower(strings.TrimSpace(valor)))
+}
+
+// ValidateRequired valida los campos del auto y devuelve un error por cada regla que no
+// se cumple. El error es el mismo ErroresValidacion, o nil si el auto es válido. Como
+// efecto, completa el estado por defecto y normaliza los campos con lista cerrada. La
+// moneda debe ser la de referencia o una con tipo de cambio cargado en tipos.
+func (a *Auto) ValidateRequired(tipos TiposCambio) (ErroresValidacion, error) {
+	v := &validador{}
+
+	v.requerido("marca", a.Marca)
+	v.requerido("modelo", a.Modelo)
+	v.requerido("version", a.Version)
+	v.requerido("tipo_venta", a.TipoVenta)
+	v.requerido("ciudad", a.Ciudad)
+	v.requerido("sucursal", a.Sucursal)
+	v.requerido("garantia", a.Garantia)
+
+	v.enum("transmision", &a.Transmision, Transmisiones)
+	v.enum("traccion", &a.Traccion, Tracciones)
+	v.enum("tipo_combustible", &a.TipoCombustible, TiposCombustible)
+	a.Moneda = NormalizarMoneda(a.Moneda)
+	v.enum("moneda", &a.Moneda, tipos.Monedas())
+
+	// Se aceptan modelos del año siguiente, que se venden desde mitad de año
+	if a.Año == 0 {
+		v.agregar("año", ReglaRequerido, "es obligatorio", nil)
+	} else {
+		v.rango("año", a.Año, AñoMinimo, time.Now().Year()+1)
+	}
+	v.rango("kilometraje", a.Kilometraje, 0, KilometrajeMaximo)
+
+	if a.Precio <= 0 {
+		v.agregar("precio", ReglaMin, "debe ser mayor a cero", 0)
+	}
+
+	// Validar estado
+	if a.Estado == "" {
+		a.Estado = EstadoDisponible
+	} else if !EsEstadoValido(a.Estado) {
+		v.agregar("estado", ReglaEnum, "debe ser uno de: "+strings.Join(EstadosValidos, ", "), EstadosValidos)
+	}
+
+	// El descuento no puede ser negativo ni dejar el precio en cero o menos
+	if a.Descuento < 0 {
+		v.agregar("descuento", ReglaMin, "no puede ser negativo", 0)
+	} else if a.Precio > 0 && a.Descuento >= a.Precio {
+		v.agregar("descuento", ReglaMax, "debe ser menor al precio", a.Precio)
+	}
+
+	// Validar que haya al menos un equipamiento destacado
+	if len(a.EquipamientoDestacado) == 0 {
+		v.agregar("equipamiento_destacado", ReglaRequerido, "debe tener al menos un elemento", nil)
+	}
+
+	if a.Imagen_Portada != "" {
+		v.url("imagen_portada", a.Imagen_Portada)
+	}
+	v.urls("imagenes", a.Imagenes)
+	v.urls("imagenes_imperfecciones", a.Imagenes_Imperfecciones)
+
+	if len(v.errores) > 0 {
+		return v.errores, v.errores
+	}
+	return nil, nil
+}
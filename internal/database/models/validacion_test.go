@@ -0,0 +1,107 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func autoValido() Auto {
+	return Auto{
+		Marca:                 "Toyota",
+		Modelo:                "Corolla",
+		Version:               "1.8 XEi",
+		TipoVenta:             "usado",
+		Año:                   2019,
+		Kilometraje:           40000,
+		Precio:                10000,
+		Ciudad:                "Córdoba",
+		Transmision:           "manual",
+		Traccion:              "4x2",
+		Sucursal:              "Centro",
+		Garantia:              "3 meses",
+		TipoCombustible:       "nafta",
+		Moneda:                "USD",
+		EquipamientoDestacado: []string{"ABS"},
+	}
+}
+
+// tiposPrueba son los tipos de cambio cargados en las pruebas de validación
+var tiposPrueba = TiposCambio{"ARS": 1000}
+
+func TestValidateRequired(t *testing.T) {
+	casos := []struct {
+		nombre    string
+		modificar func(a *Auto)
+		campo     string
+		regla     string
+	}{
+		{"marca vacía", func(a *Auto) { a.Marca = " " }, "marca", ReglaRequerido},
+		{"año sin cargar", func(a *Auto) { a.Año = 0 }, "año", ReglaRequerido},
+		{"año muy viejo", func(a *Auto) { a.Año = 1900 }, "año", ReglaMin},
+		{"año futuro", func(a *Auto) { a.Año = time.Now().Year() + 2 }, "año", ReglaMax},
+		{"kilometraje negativo", func(a *Auto) { a.Kilometraje = -1 }, "kilometraje", ReglaMin},
+		{"kilometraje excesivo", func(a *Auto) { a.Kilometraje = KilometrajeMaximo + 1 }, "kilometraje", ReglaMax},
+		{"precio cero", func(a *Auto) { a.Precio = 0 }, "precio", ReglaMin},
+		{"tracción desconocida", func(a *Auto) { a.Traccion = "6x6" }, "traccion", ReglaEnum},
+		{"combustible vacío", func(a *Auto) { a.TipoCombustible = "" }, "tipo_combustible", ReglaRequerido},
+		{"moneda sin tipo de cambio", func(a *Auto) { a.Moneda = "EUR" }, "moneda", ReglaEnum},
+		{"estado desconocido", func(a *Auto) { a.Estado = "perdido" }, "estado", ReglaEnum},
+		{"descuento mayor al precio", func(a *Auto) { a.Descuento = 10000 }, "descuento", ReglaMax},
+		{"sin equipamiento", func(a *Auto) { a.EquipamientoDestacado = nil }, "equipamiento_destacado", ReglaRequerido},
+		{"imagen sin esquema", func(a *Auto) { a.Imagenes = []string{"img.example.com/1.jpg"} }, "imagenes[0]", ReglaFormato},
+		{"portada ftp", func(a *Auto) { a.Imagen_Portada = "ftp://img.example.com/1.jpg" }, "imagen_portada", ReglaFormato},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			auto := autoValido()
+			caso.modificar(&auto)
+			errores, err := auto.ValidateRequired(tiposPrueba)
+			if err == nil || len(errores) != 1 {
+				t.Fatalf("errores = %+v", errores)
+			}
+			if errores[0].Campo != caso.campo || errores[0].Regla != caso.regla {
+				t.Errorf("error = %+v, se esperaba %s/%s", errores[0], caso.campo, caso.regla)
+			}
+		})
+	}
+}
+
+func TestValidateRequiredNormaliza(t *testing.T) {
+	auto := autoValido()
+	auto.Transmision = "Automatica"
+	auto.TipoCombustible = "DIESEL"
+	auto.Moneda = " ars "
+	auto.Imagenes = []string{"https://img.example.com/1.jpg"}
+
+	if errores, err := auto.ValidateRequired(tiposPrueba); err != nil {
+		t.Fatalf("errores = %+v", errores)
+	}
+	if auto.Transmision != "automática" || auto.TipoCombustible != "diésel" || auto.Moneda != "ARS" || auto.Estado != EstadoDisponible {
+		t.Errorf("auto normalizado = %q, %q, %q, %q", auto.Transmision, auto.TipoCombustible, auto.Moneda, auto.Estado)
+	}
+}
+
+func TestValidateRequiredMonedasConTipoDeCambio(t *testing.T) {
+	auto := autoValido()
+	auto.Moneda = "eur"
+	if errores, err := auto.ValidateRequired(TiposCambio{"ARS": 1000, "EUR": 0.9}); err != nil {
+		t.Fatalf("errores = %+v", errores)
+	}
+	if auto.Moneda != "EUR" {
+		t.Errorf("moneda = %q, se esperaba EUR", auto.Moneda)
+	}
+
+	// La moneda de referencia no necesita tipo de cambio
+	auto = autoValido()
+	if errores, err := auto.ValidateRequired(nil); err != nil {
+		t.Fatalf("errores = %+v", errores)
+	}
+	auto.Moneda = "ARS"
+	errores, err := auto.ValidateRequired(nil)
+	if err == nil || len(errores) != 1 || errores[0].Campo != "moneda" {
+		t.Fatalf("errores = %+v", errores)
+	}
+	if monedas, _ := errores[0].Parametro.([]string); len(monedas) != 1 || monedas[0] != MonedaReferencia {
+		t.Errorf("monedas aceptadas = %v", errores[0].Parametro)
+	}
+}
@@ -0,0 +1,733 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fechaPrueba es una fecha sin fracciones de milisegundo, que MongoDB no guarda
+var fechaPrueba = time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+// autoPrueba arma un auto válido disponible con el stock_id, la marca y el precio indicados
+func autoPrueba(stockID string, marca string, precio float64) models.Auto {
+	auto := models.Auto{
+		StockID:               stockID,
+		Marca:                 marca,
+		Modelo:                "Modelo " + stockID,
+		Version:               "1.6 Manual",
+		TipoVenta:             "usado",
+		Año:                   2018,
+		Kilometraje:           50000,
+		Precio:                precio,
+		PrecioLista:           precio,
+		Ciudad:                "Córdoba",
+		Transmision:           "manual",
+		Traccion:              "4x2",
+		Sucursal:              "Centro",
+		Garantia:              "3 meses",
+		Estado:                models.EstadoDisponible,
+		TipoCombustible:       "nafta",
+		Moneda:                "USD",
+		EquipamientoDestacado: []string{"Aire acondicionado"},
+		CreatedAt:             fechaPrueba,
+		UpdatedAt:             fechaPrueba,
+	}
+	auto.ActualizarCaracteristicasTexto()
+	return auto
+}
+
+// crearAutos guarda los autos en el repositorio y falla el test si no puede
+func crearAutos(t *testing.T, repo AutoRepository, autos ...models.Auto) {
+	t.Helper()
+	for _, auto := range autos {
+		if err := repo.Create(context.Background(), auto); err != nil {
+			t.Fatalf("Create(%s): %v", auto.StockID, err)
+		}
+	}
+}
+
+// stockIDsDe devuelve los stock_id de los autos en orden
+func stockIDsDe[T models.Auto | models.AutoPublico](autos []T) []string {
+	stockIDs := []string{}
+	for _, auto := range autos {
+		switch a := any(auto).(type) {
+		case models.Auto:
+			stockIDs = append(stockIDs, a.StockID)
+		case models.AutoPublico:
+			stockIDs = append(stockIDs, a.StockID)
+		}
+	}
+	return stockIDs
+}
+
+func igualesStockIDs(t *testing.T, obtenidos []string, esperados ...string) {
+	t.Helper()
+	if len(obtenidos) != len(esperados) {
+		t.Fatalf("stock_id = %v, se esperaba %v", obtenidos, esperados)
+	}
+	for i := range esperados {
+		if obtenidos[i] != esperados[i] {
+			t.Fatalf("stock_id = %v, se esperaba %v", obtenidos, esperados)
+		}
+	}
+}
+
+// probarContrato corre los casos que cumple toda implementación de AutoRepository.
+// nuevo debe devolver un repositorio vacío en cada llamada.
+func probarContrato(t *testing.T, nuevo func(t *testing.T) AutoRepository) {
+	ctx := context.Background()
+
+	t.Run("FindByStockID", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000))
+
+		auto, err := repo.FindByStockID(ctx, "T0001")
+		if err != nil {
+			t.Fatalf("FindByStockID: %v", err)
+		}
+		if auto.Marca != "Toyota" || auto.Precio != 10000 || !auto.CreatedAt.Equal(fechaPrueba) {
+			t.Errorf("auto leído = %+v", auto)
+		}
+		if _, err := repo.FindByStockID(ctx, "T0002"); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("FindByStockID inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+	})
+
+	t.Run("Create rechaza stock_id duplicado", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000))
+
+		if err := repo.Create(ctx, autoPrueba("T0001", "Toyota", 20000)); !errors.Is(err, ErrStockIDDuplicado) {
+			t.Errorf("Create duplicado: err = %v, se esperaba ErrStockIDDuplicado", err)
+		}
+	})
+
+	t.Run("FindAll ordena por stock_id", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0002", "Toyota", 1), autoPrueba("F0001", "Ford", 1), autoPrueba("T0001", "Toyota", 1))
+
+		autos, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("FindAll: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "F0001", "T0001", "T0002")
+	})
+
+	t.Run("Update y Delete", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000))
+		auto, _ := repo.FindByStockID(ctx, "T0001")
+		if auto.Revision != 1 {
+			t.Fatalf("revisión al crear = %d, se esperaba 1", auto.Revision)
+		}
+
+		auto.Kilometraje = 60000
+		if err := repo.Update(ctx, auto); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+		leido, _ := repo.FindByStockID(ctx, "T0001")
+		if leido.Kilometraje != 60000 || leido.Revision != 2 {
+			t.Errorf("kilometraje = %d, revisión = %d, se esperaba 60000 y 2", leido.Kilometraje, leido.Revision)
+		}
+		if err := repo.Update(ctx, auto); !errors.Is(err, ErrRevisionCambiada) {
+			t.Errorf("Update con revisión vieja: err = %v, se esperaba ErrRevisionCambiada", err)
+		}
+		if err := repo.Update(ctx, autoPrueba("T0009", "Toyota", 1)); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("Update inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+
+		if err := repo.Delete(ctx, "T0001", 1, fechaPrueba); !errors.Is(err, ErrRevisionCambiada) {
+			t.Errorf("Delete con revisión vieja: err = %v, se esperaba ErrRevisionCambiada", err)
+		}
+		if err := repo.Delete(ctx, "T0001", 2, fechaPrueba); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		if err := repo.Delete(ctx, "T0001", 3, fechaPrueba); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("Delete repetido: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+	})
+
+	t.Run("Papelera, Restaurar y Purgar", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo,
+			autoPrueba("T0001", "Toyota", 10000),
+			autoPrueba("T0002", "Toyota", 20000),
+			autoPrueba("F0001", "Ford", 15000),
+		)
+		if err := repo.Delete(ctx, "T0001", 1, fechaPrueba); err != nil {
+			t.Fatalf("Delete T0001: %v", err)
+		}
+		if err := repo.Delete(ctx, "F0001", 1, fechaPrueba.AddDate(0, 0, 10)); err != nil {
+			t.Fatalf("Delete F0001: %v", err)
+		}
+
+		// Los autos en la papelera no aparecen en ninguna lectura ni aceptan escrituras
+		if _, err := repo.FindByStockID(ctx, "T0001"); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("FindByStockID en la papelera: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+		todos, _ := repo.FindAll(ctx)
+		igualesStockIDs(t, stockIDsDe(todos), "T0002")
+		filtrados, _ := repo.Filter(ctx, Consulta{Filtro: bson.M{}})
+		igualesStockIDs(t, stockIDsDe(filtrados), "T0002")
+		if total, _ := repo.Count(ctx, Consulta{Filtro: bson.M{}}); total != 1 {
+			t.Errorf("Count = %d, se esperaba 1", total)
+		}
+		if facetas, _ := repo.Facets(ctx, ConsultaFacetas{Filtro: bson.M{}}); facetas.Total != 1 {
+			t.Errorf("Facets.Total = %d, se esperaba 1", facetas.Total)
+		}
+		if estadisticas, _ := repo.Estadisticas(ctx, fechaPrueba); estadisticas.PorSucursal["Centro"] != 1 {
+			t.Errorf("Estadisticas.PorSucursal = %v, se esperaba 1 auto", estadisticas.PorSucursal)
+		}
+		if err := repo.SetFeatured(ctx, "T0001", true); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("SetFeatured en la papelera: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+
+		papelera, err := repo.Papelera(ctx)
+		if err != nil {
+			t.Fatalf("Papelera: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(papelera), "F0001", "T0001")
+
+		restaurado, err := repo.Restaurar(ctx, "T0001")
+		if err != nil {
+			t.Fatalf("Restaurar: %v", err)
+		}
+		if restaurado.DeletedAt == nil || !restaurado.DeletedAt.Equal(fechaPrueba) {
+			t.Errorf("Restaurar devolvió deleted_at = %v, se esperaba %v", restaurado.DeletedAt, fechaPrueba)
+		}
+		auto, err := repo.FindByStockID(ctx, "T0001")
+		if err != nil || auto.DeletedAt != nil || auto.Revision != 3 {
+			t.Errorf("auto restaurado = %+v, err = %v", auto, err)
+		}
+		if _, err := repo.Restaurar(ctx, "T0001"); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("Restaurar fuera de la papelera: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+
+		// Solo se purgan los autos borrados antes de la fecha
+		if purgados, err := repo.Purgar(ctx, fechaPrueba.AddDate(0, 0, 5)); err != nil || purgados != 0 {
+			t.Errorf("Purgar: purgados = %d, err = %v, se esperaba 0", purgados, err)
+		}
+		if purgados, err := repo.Purgar(ctx, fechaPrueba.AddDate(0, 0, 11)); err != nil || purgados != 1 {
+			t.Errorf("Purgar: purgados = %d, err = %v, se esperaba 1", purgados, err)
+		}
+		if papelera, _ := repo.Papelera(ctx); len(papelera) != 0 {
+			t.Errorf("papelera después de purgar = %v", stockIDsDe(papelera))
+		}
+		todos, _ = repo.FindAll(ctx)
+		igualesStockIDs(t, stockIDsDe(todos), "T0001", "T0002")
+	})
+
+	t.Run("SetFeatured", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000))
+
+		if err := repo.SetFeatured(ctx, "T0001", true); err != nil {
+			t.Fatalf("SetFeatured: %v", err)
+		}
+		auto, _ := repo.FindByStockID(ctx, "T0001")
+		if !auto.Featured || auto.Revision != 2 {
+			t.Errorf("featured = %v, revisión = %d, se esperaba true y 2", auto.Featured, auto.Revision)
+		}
+		if err := repo.SetFeatured(ctx, "T0009", true); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("SetFeatured inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+	})
+
+	t.Run("Reservas", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000))
+
+		ana := models.Reserva{ID: "A123", Nombre: "Ana", Apellido: "Pérez", FechaHora: fechaPrueba}
+		auto, err := repo.AgregarReserva(ctx, "T0001", ana)
+		if err != nil {
+			t.Fatalf("AgregarReserva: %v", err)
+		}
+		if len(auto.Reservas) != 1 || auto.Reservas[0].ID != "A123" || auto.Revision != 2 {
+			t.Errorf("auto con reserva: reservas = %+v, revisión = %d", auto.Reservas, auto.Revision)
+		}
+		luis := models.Reserva{ID: "B456", Nombre: "Luis", Apellido: "Gómez", FechaHora: fechaPrueba}
+		if auto, _ = repo.AgregarReserva(ctx, "T0001", luis); len(auto.Reservas) != 2 {
+			t.Errorf("reservas = %+v, se esperaban 2", auto.Reservas)
+		}
+		if _, err := repo.AgregarReserva(ctx, "T0001", models.Reserva{ID: "C789", Nombre: "Ana", Apellido: "Pérez"}); !errors.Is(err, ErrReservaDuplicada) {
+			t.Errorf("AgregarReserva duplicada: err = %v, se esperaba ErrReservaDuplicada", err)
+		}
+		if _, err := repo.AgregarReserva(ctx, "T0009", ana); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("AgregarReserva inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+
+		ana.Telefono = "1155550000"
+		if err := repo.EditarReserva(ctx, "T0001", ana); err != nil {
+			t.Fatalf("EditarReserva: %v", err)
+		}
+		if err := repo.EditarReserva(ctx, "T0001", models.Reserva{ID: "X000"}); !errors.Is(err, ErrReservaNoEncontrada) {
+			t.Errorf("EditarReserva inexistente: err = %v, se esperaba ErrReservaNoEncontrada", err)
+		}
+		if err := repo.EliminarReserva(ctx, "T0001", "B456"); err != nil {
+			t.Fatalf("EliminarReserva: %v", err)
+		}
+		if err := repo.EliminarReserva(ctx, "T0001", "B456"); !errors.Is(err, ErrReservaNoEncontrada) {
+			t.Errorf("EliminarReserva repetida: err = %v, se esperaba ErrReservaNoEncontrada", err)
+		}
+		if err := repo.EliminarReserva(ctx, "T0009", "A123"); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("EliminarReserva de auto inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+
+		auto, _ = repo.FindByStockID(ctx, "T0001")
+		if len(auto.Reservas) != 1 || auto.Reservas[0].Telefono != "1155550000" || auto.Revision != 5 {
+			t.Errorf("reservas = %+v, revisión = %d", auto.Reservas, auto.Revision)
+		}
+	})
+
+	t.Run("UpdatePrecios guarda y quita la promoción", func(t *testing.T) {
+		repo := nuevo(t)
+		auto := autoPrueba("T0001", "Toyota", 10000)
+		auto.IniciarHistorialPrecios(fechaPrueba)
+		crearAutos(t, repo, auto)
+		auto.Revision = 1
+
+		auto.Promocion = &models.Promocion{Tipo: models.DescuentoPorcentaje, Valor: 10, CreadoEn: fechaPrueba}
+		auto.CambioPrecio(9000, models.MotivoPrecioDescuento, fechaPrueba)
+		if err := repo.UpdatePrecios(ctx, auto); err != nil {
+			t.Fatalf("UpdatePrecios: %v", err)
+		}
+		// Un segundo cambio leído antes del primero ya no está en la revisión guardada
+		if err := repo.UpdatePrecios(ctx, auto); !errors.Is(err, ErrRevisionCambiada) {
+			t.Errorf("UpdatePrecios con revisión vieja: err = %v, se esperaba ErrRevisionCambiada", err)
+		}
+
+		guardado, _ := repo.FindByStockID(ctx, "T0001")
+		if guardado.Promocion == nil || guardado.PrecioAnterior != 10000 || !guardado.BajoDePrecio || len(guardado.HistorialPrecios) != 2 {
+			t.Errorf("auto con descuento = %+v", guardado)
+		}
+		autos, err := repo.Filter(ctx, Consulta{Filtro: bson.M{"precio": bson.M{"$lt": 9500}}})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		if len(autos) != 1 || autos[0].Precio != 9000 || autos[0].Descuento != 1000 {
+			t.Errorf("Filter por precio efectivo = %+v", autos)
+		}
+
+		guardado.Promocion = nil
+		guardado.CambioPrecio(10000, models.MotivoPrecioSinDescuento, fechaPrueba)
+		guardado.Descuento = 0
+		if err := repo.UpdatePrecios(ctx, guardado); err != nil {
+			t.Fatalf("UpdatePrecios: %v", err)
+		}
+		sinDescuento, _ := repo.FindByStockID(ctx, "T0001")
+		if sinDescuento.Promocion != nil || sinDescuento.PrecioAnterior != 0 || !sinDescuento.BajoDePrecioEn.IsZero() {
+			t.Errorf("auto sin descuento = %+v", sinDescuento)
+		}
+	})
+
+	t.Run("Ventanas de descuento cambian la baja de precio al leer", func(t *testing.T) {
+		repo := nuevo(t)
+		now := time.Now().Truncate(time.Millisecond)
+		haceUnaHora := now.Add(-time.Hour)
+		enUnaHora := now.Add(time.Hour)
+		haceDosHoras := now.Add(-2 * time.Hour)
+
+		// Se guardó antes de que abriera la ventana, con el precio de lista
+		abierta := autoPrueba("T0001", "Toyota", 10000)
+		abierta.UpdatedAt = haceDosHoras
+		abierta.Promocion = &models.Promocion{Tipo: models.DescuentoPorcentaje, Valor: 10, ValidoDesde: &haceUnaHora, ValidoHasta: &enUnaHora, CreadoEn: haceDosHoras}
+
+		// Se guardó con el descuento vigente y la ventana ya cerró
+		cerrada := autoPrueba("T0002", "Toyota", 10000)
+		cerrada.UpdatedAt = haceDosHoras
+		cerrada.Promocion = &models.Promocion{Tipo: models.DescuentoMonto, Valor: 1000, ValidoDesde: &haceDosHoras, ValidoHasta: &haceUnaHora, CreadoEn: haceDosHoras}
+		cerrada.CambioPrecio(9000, models.MotivoPrecioDescuento, haceDosHoras)
+
+		// Bajó de precio de lista y no tiene descuento
+		lista := autoPrueba("T0003", "Toyota", 10000)
+		lista.UpdatedAt = haceDosHoras
+		lista.CambioPrecio(8000, models.MotivoPrecioActualizacion, haceDosHoras)
+		lista.PrecioLista = 8000
+		crearAutos(t, repo, abierta, cerrada, lista)
+
+		autos, err := repo.Filter(ctx, Consulta{Filtro: bson.M{"bajo_de_precio": true}, Orden: bson.D{{Key: "stock_id", Value: 1}}})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "T0001", "T0003")
+		if autos[0].Precio != 9000 || autos[0].PrecioAnterior != 10000 || !autos[0].BajoDePrecioEn.Equal(haceUnaHora) {
+			t.Errorf("ventana abierta = precio %v, anterior %v, bajó en %v", autos[0].Precio, autos[0].PrecioAnterior, autos[0].BajoDePrecioEn)
+		}
+		if autos[1].PrecioAnterior != 10000 || !autos[1].BajoDePrecioEn.Equal(haceDosHoras) {
+			t.Errorf("baja de lista = anterior %v, bajó en %v", autos[1].PrecioAnterior, autos[1].BajoDePrecioEn)
+		}
+
+		autos, err = repo.Filter(ctx, Consulta{Filtro: bson.M{"stock_id": "T0002"}})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		if len(autos) != 1 || autos[0].Precio != 10000 || autos[0].BajoDePrecio || autos[0].PrecioAnterior != 0 || !autos[0].BajoDePrecioEn.IsZero() {
+			t.Errorf("ventana cerrada = %+v", autos)
+		}
+
+		// Lo mismo al leer el auto guardado y calcular en Go
+		auto, _ := repo.FindByStockID(ctx, "T0002")
+		auto.AplicarPrecioEfectivo(now)
+		if auto.BajoDePrecio || auto.Precio != 10000 {
+			t.Errorf("AplicarPrecioEfectivo con ventana cerrada: precio %v, bajo de precio %v", auto.Precio, auto.BajoDePrecio)
+		}
+	})
+
+	t.Run("Filter con regex, orden y páginas", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo,
+			autoPrueba("T0001", "Toyota", 30000),
+			autoPrueba("T0002", "Toyota", 10000),
+			autoPrueba("T0003", "Toyota", 20000),
+			autoPrueba("F0001", "Ford", 15000),
+		)
+
+		consulta := Consulta{
+			Filtro: bson.M{"marca": bson.M{"$in": bson.A{primitive.Regex{Pattern: "^toy", Options: "i"}}}},
+			Orden:  bson.D{{Key: "precio_normalizado", Value: -1}, {Key: "stock_id", Value: 1}},
+		}
+		autos, err := repo.FilterPublic(ctx, consulta)
+		if err != nil {
+			t.Fatalf("FilterPublic: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "T0001", "T0003", "T0002")
+
+		total, err := repo.Count(ctx, consulta)
+		if err != nil || total != 3 {
+			t.Errorf("Count = %d, %v; se esperaba 3", total, err)
+		}
+
+		consulta.Saltar = 1
+		consulta.Limite = 1
+		autos, _ = repo.FilterPublic(ctx, consulta)
+		igualesStockIDs(t, stockIDsDe(autos), "T0003")
+
+		excluidos, _ := repo.Filter(ctx, Consulta{
+			Filtro: bson.M{"marca": bson.M{"$nin": bson.A{primitive.Regex{Pattern: "^toyota$", Options: "i"}}}},
+		})
+		igualesStockIDs(t, stockIDsDe(excluidos), "F0001")
+	})
+
+	t.Run("Filter desde un cursor", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 1), autoPrueba("T0002", "Toyota", 1), autoPrueba("T0003", "Toyota", 1))
+
+		autos, err := repo.Filter(ctx, Consulta{
+			Desde: bson.M{"$or": bson.A{bson.M{"stock_id": bson.M{"$gt": "T0001"}}}},
+			Orden: bson.D{{Key: "stock_id", Value: 1}},
+		})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "T0002", "T0003")
+	})
+
+	t.Run("Filter con búsqueda de texto sin acentos", func(t *testing.T) {
+		repo := nuevo(t)
+		automatico := autoPrueba("T0001", "Toyota", 1)
+		automatico.Version = "2.0 Automática"
+		crearAutos(t, repo, automatico, autoPrueba("F0001", "Ford", 1))
+
+		autos, err := repo.Filter(ctx, Consulta{
+			Filtro:     bson.M{"$text": bson.M{"$search": "automatica", "$language": "spanish", "$diacriticSensitive": false}},
+			Relevancia: true,
+		})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "T0001")
+	})
+
+	t.Run("Facets", func(t *testing.T) {
+		repo := nuevo(t)
+		viejo := autoPrueba("F0001", "Ford", 4000)
+		viejo.Año = 1995
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 12000), autoPrueba("T0002", "Toyota", 12000), viejo)
+
+		resultado, err := repo.Facets(ctx, ConsultaFacetas{
+			Filtro:      bson.M{"marca": "Toyota"},
+			Categoricas: []string{"marca"},
+			Rangos:      []Rango{{Nombre: "precio", Campo: "precio_normalizado", Limites: []float64{0, 5000, 10000, 15000}}},
+		})
+		if err != nil {
+			t.Fatalf("Facets: %v", err)
+		}
+		if resultado.Total != 2 {
+			t.Errorf("total = %d, se esperaba 2", resultado.Total)
+		}
+		marcas := resultado.Conteo["marca"]
+		if len(marcas) != 2 || marcas[0].Valor != "Toyota" || marcas[0].Cantidad != 2 || marcas[1].Valor != "Ford" {
+			t.Errorf("faceta marca = %+v", marcas)
+		}
+		precios := resultado.Conteo["precio"]
+		if len(precios) != 1 || precios[0].Valor != 10000.0 || precios[0].Cantidad != 2 {
+			t.Errorf("faceta precio = %+v", precios)
+		}
+	})
+
+	t.Run("Monedas", func(t *testing.T) {
+		repo := nuevo(t)
+		pesos := autoPrueba("T0002", "Toyota", 1)
+		pesos.Moneda = "ARS"
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 1), pesos, autoPrueba("T0003", "Toyota", 1))
+
+		monedas, err := repo.Monedas(ctx)
+		if err != nil {
+			t.Fatalf("Monedas: %v", err)
+		}
+		if len(monedas) != 2 {
+			t.Errorf("monedas = %v, se esperaban ARS y USD", monedas)
+		}
+	})
+
+	t.Run("Estadisticas", func(t *testing.T) {
+		repo := nuevo(t)
+		destacado := autoPrueba("T0001", "Toyota", 1)
+		destacado.Featured = true
+		destacado.Reservas = []models.Reserva{
+			{ID: "A001", Nombre: "Ana", FechaHora: fechaPrueba.Add(48 * time.Hour)},
+			{ID: "A002", Nombre: "Juan", FechaHora: fechaPrueba.Add(-48 * time.Hour)},
+		}
+		vendido := autoPrueba("T0002", "Toyota", 1)
+		vendido.Estado = models.EstadoVendido
+		vendido.Sucursal = "Norte"
+		reciente := autoPrueba("F0001", "Ford", 1)
+		reciente.CreatedAt = fechaPrueba.Add(10 * 24 * time.Hour)
+		crearAutos(t, repo, destacado, vendido, reciente)
+
+		estadisticas, err := repo.Estadisticas(ctx, fechaPrueba.Add(20*24*time.Hour))
+		if err != nil {
+			t.Fatalf("Estadisticas: %v", err)
+		}
+		if estadisticas.PorEstado[models.EstadoDisponible] != 2 || estadisticas.PorEstado[models.EstadoVendido] != 1 {
+			t.Errorf("por estado = %v", estadisticas.PorEstado)
+		}
+		if estadisticas.PorSucursal["Centro"] != 2 || estadisticas.PorSucursal["Norte"] != 1 {
+			t.Errorf("por sucursal = %v", estadisticas.PorSucursal)
+		}
+		if estadisticas.Destacados != 1 || estadisticas.ReservasActivas != 0 {
+			t.Errorf("destacados = %d, reservas activas = %d", estadisticas.Destacados, estadisticas.ReservasActivas)
+		}
+		// Los autos no vendidos llevan 20 y 10 días en stock
+		if estadisticas.DiasEnStockPromedio != 15 {
+			t.Errorf("días en stock = %v, se esperaba 15", estadisticas.DiasEnStockPromedio)
+		}
+
+		activas, _ := repo.Estadisticas(ctx, fechaPrueba)
+		if activas.ReservasActivas != 1 {
+			t.Errorf("reservas activas = %d, se esperaba 1", activas.ReservasActivas)
+		}
+	})
+
+	t.Run("NextStockIDNumber", func(t *testing.T) {
+		repo := nuevo(t)
+		for esperado := int64(1); esperado <= 3; esperado++ {
+			num, err := repo.NextStockIDNumber(ctx, "T")
+			if err != nil || num != esperado {
+				t.Fatalf("NextStockIDNumber = %d, %v; se esperaba %d", num, err, esperado)
+			}
+		}
+		if num, _ := repo.NextStockIDNumber(ctx, "F"); num != 1 {
+			t.Errorf("NextStockIDNumber de otro prefijo = %d, se esperaba 1", num)
+		}
+	})
+
+	t.Run("CambiarEstado", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 1))
+
+		transicion := models.TransicionEstado{Desde: models.EstadoDisponible, Hacia: models.EstadoEnNegociacion, Fecha: fechaPrueba}
+		info := models.InfoEstado{EnNegociacion: &models.NegociacionInfo{Nombre: "Ana"}}
+		if err := repo.CambiarEstado(ctx, "T0001", 1, transicion, info); err != nil {
+			t.Fatalf("CambiarEstado: %v", err)
+		}
+		auto, _ := repo.FindByStockID(ctx, "T0001")
+		if auto.Estado != models.EstadoEnNegociacion || auto.EnNegociacion == nil || len(auto.HistorialEstados) != 1 {
+			t.Errorf("auto en negociación = %+v", auto)
+		}
+
+		if err := repo.CambiarEstado(ctx, "T0001", 1, transicion, info); !errors.Is(err, ErrRevisionCambiada) {
+			t.Errorf("CambiarEstado con revisión vieja: err = %v, se esperaba ErrRevisionCambiada", err)
+		}
+		if err := repo.CambiarEstado(ctx, "T0009", 1, transicion, info); !errors.Is(err, ErrNoEncontrado) {
+			t.Errorf("CambiarEstado inexistente: err = %v, se esperaba ErrNoEncontrado", err)
+		}
+	})
+
+	t.Run("AsignarCampania y QuitarCampania", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000), autoPrueba("T0002", "Toyota", 10000))
+
+		campania := models.PromocionCampania{
+			Promocion:   models.Promocion{Tipo: models.DescuentoMonto, Valor: 500, CreadoEn: fechaPrueba},
+			CampaniaID:  "c1",
+			Precedencia: models.PrecedenciaMejorPrecio,
+		}
+		if err := repo.AsignarCampania(ctx, []string{"T0001"}, campania, fechaPrueba); err != nil {
+			t.Fatalf("AsignarCampania: %v", err)
+		}
+		autos, _ := repo.Filter(ctx, Consulta{Filtro: bson.M{"descuento": bson.M{"$gt": 0}}})
+		igualesStockIDs(t, stockIDsDe(autos), "T0001")
+
+		auto, _ := repo.FindByStockID(ctx, "T0001")
+		if ultimo := auto.HistorialPrecios[len(auto.HistorialPrecios)-1]; ultimo.Precio != 9500 || ultimo.Motivo != models.MotivoPrecioCampania {
+			t.Errorf("último punto al asignar = %+v, se esperaba 9500 por campaña", ultimo)
+		}
+
+		quitados, err := repo.QuitarCampania(ctx, "c1", fechaPrueba)
+		if err != nil || quitados != 1 {
+			t.Errorf("QuitarCampania = %d, %v; se esperaba 1", quitados, err)
+		}
+		auto, _ = repo.FindByStockID(ctx, "T0001")
+		if auto.Campania != nil {
+			t.Errorf("campania = %+v, se esperaba nil", auto.Campania)
+		}
+		if ultimo := auto.HistorialPrecios[len(auto.HistorialPrecios)-1]; len(auto.HistorialPrecios) != 3 || ultimo.Precio != 10000 || ultimo.Motivo != models.MotivoPrecioSinCampania {
+			t.Errorf("serie de precios al quitar = %+v", auto.HistorialPrecios)
+		}
+	})
+
+	t.Run("AsignarCampania reemplaza la campaña anterior", func(t *testing.T) {
+		repo := nuevo(t)
+		crearAutos(t, repo, autoPrueba("T0001", "Toyota", 10000), autoPrueba("T0002", "Toyota", 10000))
+		for _, id := range []string{"c1", "c2"} {
+			campania := models.Campania{ID: id, Nombre: id, StockIDs: []string{"T0001", "T0002"}, CreadoEn: fechaPrueba}
+			if err := repo.CrearCampania(ctx, campania); err != nil {
+				t.Fatalf("CrearCampania: %v", err)
+			}
+		}
+		promocion := func(id string, valor float64) models.PromocionCampania {
+			return models.PromocionCampania{
+				Promocion:   models.Promocion{Tipo: models.DescuentoMonto, Valor: valor, CreadoEn: fechaPrueba},
+				CampaniaID:  id,
+				Precedencia: models.PrecedenciaMejorPrecio,
+			}
+		}
+		if err := repo.AsignarCampania(ctx, []string{"T0001", "T0002"}, promocion("c1", 500), fechaPrueba); err != nil {
+			t.Fatalf("AsignarCampania c1: %v", err)
+		}
+		if err := repo.AsignarCampania(ctx, []string{"T0001"}, promocion("c2", 500), fechaPrueba); err != nil {
+			t.Fatalf("AsignarCampania c2: %v", err)
+		}
+
+		campanias, _ := repo.Campanias(ctx)
+		for _, campania := range campanias {
+			if campania.ID == "c1" {
+				igualesStockIDs(t, campania.StockIDs, "T0002")
+			}
+		}
+		// El precio no cambió al reemplazar la campaña, así que no hay un punto nuevo
+		auto, _ := repo.FindByStockID(ctx, "T0001")
+		if auto.Campania == nil || auto.Campania.CampaniaID != "c2" || len(auto.HistorialPrecios) != 2 {
+			t.Errorf("auto con la campaña reemplazada = %+v", auto)
+		}
+	})
+
+	t.Run("Campanias, CrearCampania y EliminarCampania", func(t *testing.T) {
+		repo := nuevo(t)
+		for i, id := range []string{"c1", "c2"} {
+			campania := models.Campania{
+				ID:        id,
+				Nombre:    "Campaña " + id,
+				Filtro:    map[string]string{"marca": "Toyota"},
+				Promocion: models.Promocion{Tipo: models.DescuentoPorcentaje, Valor: 10, CreadoEn: fechaPrueba},
+				StockIDs:  []string{"T0001"},
+				CreadoEn:  fechaPrueba.Add(time.Duration(i) * time.Hour),
+			}
+			if err := repo.CrearCampania(ctx, campania); err != nil {
+				t.Fatalf("CrearCampania: %v", err)
+			}
+		}
+
+		campanias, err := repo.Campanias(ctx)
+		if err != nil {
+			t.Fatalf("Campanias: %v", err)
+		}
+		if len(campanias) != 2 || campanias[0].ID != "c2" || campanias[1].Filtro["marca"] != "Toyota" {
+			t.Errorf("campañas = %+v, se esperaba c2 primero", campanias)
+		}
+
+		if err := repo.EliminarCampania(ctx, "c1"); err != nil {
+			t.Fatalf("EliminarCampania: %v", err)
+		}
+		if err := repo.EliminarCampania(ctx, "c1"); !errors.Is(err, ErrCampaniaNoEncontrada) {
+			t.Errorf("EliminarCampania repetida: err = %v, se esperaba ErrCampaniaNoEncontrada", err)
+		}
+		if campanias, _ := repo.Campanias(ctx); len(campanias) != 1 || campanias[0].ID != "c2" {
+			t.Errorf("campañas después de eliminar = %+v", campanias)
+		}
+	})
+
+	t.Run("GuardarTipoCambio, TablaTiposCambio e HistorialTipoCambio", func(t *testing.T) {
+		repo := nuevo(t)
+		ars := autoPrueba("T0001", "Toyota", 20000000)
+		ars.Moneda = "ARS"
+		crearAutos(t, repo, ars)
+
+		tipos := []models.TipoCambio{
+			{Moneda: "EUR", Tasa: 0.9, ActualizadoEn: fechaPrueba},
+			{Moneda: "ARS", Tasa: 800, ActualizadoEn: fechaPrueba},
+			{Moneda: "ARS", Tasa: 1000, ActualizadoEn: fechaPrueba.Add(time.Hour)},
+		}
+		for _, tipo := range tipos {
+			if err := repo.GuardarTipoCambio(ctx, tipo); err != nil {
+				t.Fatalf("GuardarTipoCambio: %v", err)
+			}
+		}
+
+		tabla, err := repo.TablaTiposCambio(ctx)
+		if err != nil {
+			t.Fatalf("TablaTiposCambio: %v", err)
+		}
+		if len(tabla) != 2 || tabla[0].Moneda != "ARS" || tabla[0].Tasa != 1000 || tabla[1].Moneda != "EUR" {
+			t.Errorf("tabla = %+v, se esperaba ARS 1000 y EUR", tabla)
+		}
+		historial, err := repo.HistorialTipoCambio(ctx, "ARS")
+		if err != nil {
+			t.Fatalf("HistorialTipoCambio: %v", err)
+		}
+		if len(historial) != 2 || historial[0].Tasa != 1000 || historial[1].Tasa != 800 {
+			t.Errorf("historial ARS = %+v", historial)
+		}
+
+		// La tasa vigente es la que normaliza los precios del catálogo
+		autos, err := repo.Filter(ctx, Consulta{Filtro: bson.M{"precio_normalizado": bson.M{"$lte": 20000}}})
+		if err != nil {
+			t.Fatalf("Filter: %v", err)
+		}
+		igualesStockIDs(t, stockIDsDe(autos), "T0001")
+	})
+
+	t.Run("Historial", func(t *testing.T) {
+		repo := nuevo(t)
+		registros := []models.RegistroHistorial{
+			{StockID: "T0001", Fecha: fechaPrueba, Cambios: []models.CambioCampo{{Campo: "precio", Anterior: 1.0, Nuevo: 2.0}}},
+			{StockID: "T0001", Fecha: fechaPrueba.Add(time.Hour), Cambios: []models.CambioCampo{{Campo: "estado", Anterior: "a", Nuevo: "b"}}},
+			{StockID: "T0002", Fecha: fechaPrueba, Cambios: []models.CambioCampo{{Campo: "precio", Anterior: 1.0, Nuevo: 2.0}}},
+		}
+		for _, registro := range registros {
+			if err := repo.RegistrarHistorial(ctx, registro); err != nil {
+				t.Fatalf("RegistrarHistorial: %v", err)
+			}
+		}
+
+		todos, err := repo.Historial(ctx, "T0001", "")
+		if err != nil {
+			t.Fatalf("Historial: %v", err)
+		}
+		if len(todos) != 2 || todos[0].Cambios[0].Campo != "estado" {
+			t.Errorf("historial = %+v, se esperaba el más reciente primero", todos)
+		}
+		precios, _ := repo.Historial(ctx, "T0001", "precio")
+		if len(precios) != 1 || precios[0].Cambios[0].Campo != "precio" {
+			t.Errorf("historial de precio = %+v", precios)
+		}
+	})
+}
@@ -0,0 +1,828 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Memoria es un AutoRepository que guarda los autos en memoria, pensado para los tests.
+// Los autos se guardan serializados en BSON, así las fechas y los valores vacíos se
+// comportan igual que en MongoDB. Interpreta los filtros que arma el catálogo; la
+// búsqueda de texto es una aproximación sin stemming.
+type Memoria struct {
+	mu          sync.Mutex
+	autos       map[string]bson.Raw
+	contadores  map[string]int64
+	historial   []models.RegistroHistorial
+	campanias   []models.Campania
+	tiposCambio map[string]models.TipoCambio
+	// historialCambios guarda cada tipo de cambio cargado en el orden en que se cargó
+	historialCambios []models.TipoCambio
+}
+
+// NewMemoria crea un repositorio en memoria vacío
+func NewMemoria() *Memoria {
+	return &Memoria{
+		autos:       map[string]bson.Raw{},
+		contadores:  map[string]int64{},
+		tiposCambio: map[string]models.TipoCambio{},
+	}
+}
+
+// SetTiposCambio carga las tasas con las que se calcula precio_normalizado, que en
+// MongoDB se leen de la colección tipos_cambio
+func (m *Memoria) SetTiposCambio(tipos models.TiposCambio) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tiposCambio = map[string]models.TipoCambio{}
+	for moneda, tasa := range tipos {
+		moneda = models.NormalizarMoneda(moneda)
+		m.tiposCambio[moneda] = models.TipoCambio{Moneda: moneda, Tasa: tasa}
+	}
+}
+
+func (m *Memoria) FindByStockID(ctx context.Context, stockID string) (models.Auto, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.leer(stockID)
+}
+
+func (m *Memoria) FindAll(ctx context.Context) ([]models.Auto, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	autos := make([]models.Auto, 0, len(m.autos))
+	for _, stockID := range m.stockIDs() {
+		auto, err := m.leer(stockID)
+		if err != nil {
+			return nil, err
+		}
+		autos = append(autos, auto)
+	}
+	return autos, nil
+}
+
+func (m *Memoria) Filter(ctx context.Context, consulta Consulta) ([]models.Auto, error) {
+	docs, err := m.consultar(consulta)
+	if err != nil {
+		return nil, err
+	}
+	autos := make([]models.Auto, 0, len(docs))
+	for _, doc := range docs {
+		var auto models.Auto
+		if err := copiar(doc, &auto); err != nil {
+			return nil, err
+		}
+		autos = append(autos, auto)
+	}
+	return autos, nil
+}
+
+func (m *Memoria) FilterPublic(ctx context.Context, consulta Consulta) ([]models.AutoPublico, error) {
+	docs, err := m.consultar(consulta)
+	if err != nil {
+		return nil, err
+	}
+	autos := make([]models.AutoPublico, 0, len(docs))
+	for _, doc := range docs {
+		var auto models.AutoPublico
+		if err := copiar(doc, &auto); err != nil {
+			return nil, err
+		}
+		autos = append(autos, auto)
+	}
+	return autos, nil
+}
+
+func (m *Memoria) Count(ctx context.Context, consulta Consulta) (int64, error) {
+	consulta.Saltar = 0
+	consulta.Limite = 0
+	docs, err := m.consultar(consulta)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+func (m *Memoria) Facets(ctx context.Context, consulta ConsultaFacetas) (ResultadoFacetas, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	filtro, err := normalizar(consulta.Filtro)
+	if err != nil {
+		return ResultadoFacetas{}, err
+	}
+	todos, err := m.documentos()
+	if err != nil {
+		return ResultadoFacetas{}, err
+	}
+	docs := []bson.M{}
+	for _, doc := range todos {
+		if _, ok := puntajeTexto(doc, filtro); ok {
+			docs = append(docs, doc)
+		}
+	}
+	base := sinCampo(filtro, "$text")
+
+	resultado := ResultadoFacetas{Conteo: map[string][]ConteoFaceta{}}
+	for _, doc := range docs {
+		cumple, err := coincide(doc, base)
+		if err != nil {
+			return ResultadoFacetas{}, err
+		}
+		if cumple {
+			resultado.Total++
+		}
+	}
+
+	for _, campo := range consulta.Categoricas {
+		filtroCampo := sinCampo(base, campo)
+		cantidades := map[interface{}]int{}
+		for _, doc := range docs {
+			valor, _ := valorCampo(doc, campo)
+			if valor == nil || valor == "" {
+				continue
+			}
+			cumple, err := coincide(doc, filtroCampo)
+			if err != nil {
+				return ResultadoFacetas{}, err
+			}
+			if cumple {
+				cantidades[valor]++
+			}
+		}
+		conteo := []ConteoFaceta{}
+		for valor, cantidad := range cantidades {
+			conteo = append(conteo, ConteoFaceta{Valor: valor, Cantidad: cantidad})
+		}
+		sort.Slice(conteo, func(i, j int) bool {
+			if conteo[i].Cantidad != conteo[j].Cantidad {
+				return conteo[i].Cantidad > conteo[j].Cantidad
+			}
+			return compararOrden(conteo[i].Valor, true, conteo[j].Valor, true) < 0
+		})
+		resultado.Conteo[campo] = conteo
+	}
+
+	for _, rango := range consulta.Rangos {
+		filtroCampo := sinCampo(base, rango.Campo)
+		cantidades := make([]int, len(rango.Limites))
+		otros := 0
+		for _, doc := range docs {
+			cumple, err := coincide(doc, filtroCampo)
+			if err != nil {
+				return ResultadoFacetas{}, err
+			}
+			if !cumple {
+				continue
+			}
+			valor, _ := valorCampo(doc, rango.Campo)
+			if i := indiceRango(valor, rango.Limites); i >= 0 {
+				cantidades[i]++
+			} else {
+				otros++
+			}
+		}
+		conteo := []ConteoFaceta{}
+		for i, cantidad := range cantidades {
+			if cantidad > 0 {
+				conteo = append(conteo, ConteoFaceta{Valor: rango.Limites[i], Cantidad: cantidad})
+			}
+		}
+		if otros > 0 {
+			conteo = append(conteo, ConteoFaceta{Valor: FacetaOtros, Cantidad: otros})
+		}
+		resultado.Conteo[rango.Nombre] = conteo
+	}
+	return resultado, nil
+}
+
+func (m *Memoria) Monedas(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	vistas := map[string]bool{}
+	monedas := []string{}
+	for _, stockID := range m.stockIDs() {
+		auto, err := m.leer(stockID)
+		if err != nil {
+			return nil, err
+		}
+		if !vistas[auto.Moneda] {
+			vistas[auto.Moneda] = true
+			monedas = append(monedas, auto.Moneda)
+		}
+	}
+	sort.Strings(monedas)
+	return monedas, nil
+}
+
+func (m *Memoria) TiposCambio(ctx context.Context) (models.TiposCambio, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.tasas(), nil
+}
+
+func (m *Memoria) Estadisticas(ctx context.Context, now time.Time) (Estadisticas, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	estadisticas := Estadisticas{PorEstado: map[string]int64{}, PorSucursal: map[string]int64{}}
+	dias, enStock := 0.0, 0
+	for _, stockID := range m.stockIDs() {
+		auto, err := m.leer(stockID)
+		if err != nil {
+			return Estadisticas{}, err
+		}
+		estadisticas.PorEstado[auto.EstadoActual()]++
+		estadisticas.PorSucursal[auto.Sucursal]++
+		if auto.Featured {
+			estadisticas.Destacados++
+		}
+		for _, reserva := range auto.Reservas {
+			if reserva.FechaHora.After(now) {
+				estadisticas.ReservasActivas++
+			}
+		}
+		if auto.Estado != models.EstadoVendido {
+			dias += now.Sub(auto.CreatedAt).Hours() / 24
+			enStock++
+		}
+	}
+	if enStock > 0 {
+		estadisticas.DiasEnStockPromedio = dias / float64(enStock)
+	}
+	return estadisticas, nil
+}
+
+func (m *Memoria) NextStockIDNumber(ctx context.Context, prefijo string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contadores[prefijo]++
+	return m.contadores[prefijo], nil
+}
+
+func (m *Memoria) Create(ctx context.Context, auto models.Auto) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.autos[auto.StockID]; ok {
+		return ErrStockIDDuplicado
+	}
+	auto.Revision = 1
+	return m.guardar(auto)
+}
+
+// Update reemplaza los campos del auto como el $set de MongoDB: los campos con
+// omitempty que vienen vacíos conservan el valor guardado
+func (m *Memoria) Update(ctx context.Context, auto models.Auto) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	actual, err := m.leer(auto.StockID)
+	if err != nil {
+		return err
+	}
+	if actual.Revision != auto.Revision {
+		return ErrRevisionCambiada
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(m.autos[auto.StockID], &doc); err != nil {
+		return err
+	}
+	nuevo, err := aDocumento(auto)
+	if err != nil {
+		return err
+	}
+	for campo, valor := range nuevo {
+		doc[campo] = valor
+	}
+	doc["revision"] = actual.Revision + 1
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	m.autos[auto.StockID] = data
+	return nil
+}
+
+func (m *Memoria) Delete(ctx context.Context, stockID string, revision int64, fecha time.Time) error {
+	return m.modificar(stockID, func(auto *models.Auto) error {
+		if auto.Revision != revision {
+			return ErrRevisionCambiada
+		}
+		auto.DeletedAt = &fecha
+		return nil
+	})
+}
+
+func (m *Memoria) Papelera(ctx context.Context) ([]models.Auto, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	autos := []models.Auto{}
+	for stockID := range m.autos {
+		auto, err := m.leerGuardado(stockID)
+		if err != nil {
+			return nil, err
+		}
+		if auto.DeletedAt != nil {
+			autos = append(autos, auto)
+		}
+	}
+	sort.Slice(autos, func(i, j int) bool {
+		if !autos[i].DeletedAt.Equal(*autos[j].DeletedAt) {
+			return autos[i].DeletedAt.After(*autos[j].DeletedAt)
+		}
+		return autos[i].StockID < autos[j].StockID
+	})
+	return autos, nil
+}
+
+func (m *Memoria) Restaurar(ctx context.Context, stockID string) (models.Auto, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auto, err := m.leerGuardado(stockID)
+	if err != nil {
+		return models.Auto{}, err
+	}
+	if auto.DeletedAt == nil {
+		return models.Auto{}, ErrNoEncontrado
+	}
+	restaurado := auto
+	restaurado.DeletedAt = nil
+	restaurado.Revision++
+	return auto, m.guardar(restaurado)
+}
+
+func (m *Memoria) Purgar(ctx context.Context, antesDe time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purgados int64
+	for stockID := range m.autos {
+		auto, err := m.leerGuardado(stockID)
+		if err != nil {
+			return purgados, err
+		}
+		if auto.DeletedAt != nil && auto.DeletedAt.Before(antesDe) {
+			delete(m.autos, stockID)
+			purgados++
+		}
+	}
+	return purgados, nil
+}
+
+func (m *Memoria) SetFeatured(ctx context.Context, stockID string, featured bool) error {
+	return m.modificar(stockID, func(auto *models.Auto) error {
+		auto.Featured = featured
+		return nil
+	})
+}
+
+func (m *Memoria) AgregarReserva(ctx context.Context, stockID string, reserva models.Reserva) (models.Auto, error) {
+	var actualizado *models.Auto
+	err := m.modificar(stockID, func(auto *models.Auto) error {
+		for _, existente := range auto.Reservas {
+			if existente.Nombre == reserva.Nombre && existente.Apellido == reserva.Apellido {
+				return ErrReservaDuplicada
+			}
+		}
+		auto.Reservas = append(auto.Reservas, reserva)
+		actualizado = auto
+		return nil
+	})
+	if err != nil {
+		return models.Auto{}, err
+	}
+	return *actualizado, nil
+}
+
+func (m *Memoria) EditarReserva(ctx context.Context, stockID string, reserva models.Reserva) error {
+	return m.modificar(stockID, func(auto *models.Auto) error {
+		for i := range auto.Reservas {
+			if auto.Reservas[i].ID == reserva.ID {
+				auto.Reservas[i] = reserva
+				return nil
+			}
+		}
+		return ErrReservaNoEncontrada
+	})
+}
+
+func (m *Memoria) EliminarReserva(ctx context.Context, stockID string, reservaID string) error {
+	return m.modificar(stockID, func(auto *models.Auto) error {
+		for i := range auto.Reservas {
+			if auto.Reservas[i].ID == reservaID {
+				auto.Reservas = append(auto.Reservas[:i], auto.Reservas[i+1:]...)
+				return nil
+			}
+		}
+		return ErrReservaNoEncontrada
+	})
+}
+
+func (m *Memoria) UpdatePrecios(ctx context.Context, precios models.Auto) error {
+	return m.modificar(precios.StockID, func(auto *models.Auto) error {
+		if auto.Revision != precios.Revision {
+			return ErrRevisionCambiada
+		}
+		auto.Precio = precios.Precio
+		auto.PrecioLista = precios.PrecioLista
+		auto.Descuento = precios.Descuento
+		auto.Promocion = precios.Promocion
+		auto.PrecioAnterior = precios.PrecioAnterior
+		auto.BajoDePrecio = precios.BajoDePrecio
+		auto.BajoDePrecioEn = precios.BajoDePrecioEn
+		auto.HistorialPrecios = precios.HistorialPrecios
+		auto.UpdatedAt = precios.UpdatedAt
+		return nil
+	})
+}
+
+func (m *Memoria) AsignarCampania(ctx context.Context, stockIDs []string, campania models.PromocionCampania, fecha time.Time) error {
+	for _, stockID := range stockIDs {
+		var anterior *models.PromocionCampania
+		err := m.modificar(stockID, func(auto *models.Auto) error {
+			promocion := campania
+			anterior = auto.Campania
+			auto.CambiarCampania(&promocion, fecha)
+			return nil
+		})
+		if err != nil && err != ErrNoEncontrado {
+			return err
+		}
+		if anterior != nil && anterior.CampaniaID != campania.CampaniaID {
+			m.quitarDeCampania(anterior.CampaniaID, stockID)
+		}
+	}
+	return nil
+}
+
+// quitarDeCampania saca el auto de los stock_ids de la campaña
+func (m *Memoria) quitarDeCampania(campaniaID string, stockID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.campanias {
+		if m.campanias[i].ID != campaniaID {
+			continue
+		}
+		stockIDs := []string{}
+		for _, id := range m.campanias[i].StockIDs {
+			if id != stockID {
+				stockIDs = append(stockIDs, id)
+			}
+		}
+		m.campanias[i].StockIDs = stockIDs
+	}
+}
+
+func (m *Memoria) QuitarCampania(ctx context.Context, campaniaID string, fecha time.Time) (int64, error) {
+	m.mu.Lock()
+	stockIDs := m.stockIDs()
+	m.mu.Unlock()
+
+	var modificados int64
+	for _, stockID := range stockIDs {
+		err := m.modificar(stockID, func(auto *models.Auto) error {
+			if auto.Campania == nil || auto.Campania.CampaniaID != campaniaID {
+				return errSinCambios
+			}
+			auto.CambiarCampania(nil, fecha)
+			return nil
+		})
+		switch err {
+		case nil:
+			modificados++
+		case errSinCambios, ErrNoEncontrado:
+		default:
+			return modificados, err
+		}
+	}
+	return modificados, nil
+}
+
+func (m *Memoria) CambiarEstado(ctx context.Context, stockID string, revision int64, transicion models.TransicionEstado, info models.InfoEstado) error {
+	return m.modificar(stockID, func(auto *models.Auto) error {
+		if auto.Revision != revision {
+			return ErrRevisionCambiada
+		}
+		auto.Estado = transicion.Hacia
+		auto.EstadoActualizadoEn = transicion.Fecha
+		auto.UpdatedAt = transicion.Fecha
+		auto.ReservadoPor = nil
+		auto.VendidoPor = nil
+		auto.EnNegociacion = nil
+		auto.EnMantenimiento = nil
+		switch transicion.Hacia {
+		case models.EstadoReservado:
+			auto.ReservadoPor = info.ReservadoPor
+		case models.EstadoVendido:
+			auto.VendidoPor = info.VendidoPor
+		case models.EstadoEnNegociacion:
+			auto.EnNegociacion = info.EnNegociacion
+		case models.EstadoEnMantenimiento:
+			auto.EnMantenimiento = info.EnMantenimiento
+		}
+		auto.HistorialEstados = append(auto.HistorialEstados, transicion)
+		return nil
+	})
+}
+
+func (m *Memoria) RegistrarHistorial(ctx context.Context, registro models.RegistroHistorial) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var copia models.RegistroHistorial
+	if err := copiar(registro, &copia); err != nil {
+		return err
+	}
+	m.historial = append(m.historial, copia)
+	return nil
+}
+
+func (m *Memoria) Historial(ctx context.Context, stockID string, campo string) ([]models.RegistroHistorial, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	registros := []models.RegistroHistorial{}
+	for i := len(m.historial) - 1; i >= 0; i-- {
+		registro := m.historial[i]
+		if registro.StockID != stockID || (campo != "" && !modificaCampo(registro, campo)) {
+			continue
+		}
+		registros = append(registros, registro)
+	}
+	sort.SliceStable(registros, func(i, j int) bool {
+		return registros[i].Fecha.After(registros[j].Fecha)
+	})
+	return registros, nil
+}
+
+func (m *Memoria) Campanias(ctx context.Context) ([]models.Campania, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	campanias := append([]models.Campania{}, m.campanias...)
+	sort.SliceStable(campanias, func(i, j int) bool {
+		return campanias[i].CreadoEn.After(campanias[j].CreadoEn)
+	})
+	return campanias, nil
+}
+
+func (m *Memoria) CrearCampania(ctx context.Context, campania models.Campania) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.campanias = append(m.campanias, campania)
+	return nil
+}
+
+func (m *Memoria) EliminarCampania(ctx context.Context, campaniaID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.campanias {
+		if m.campanias[i].ID == campaniaID {
+			m.campanias = append(m.campanias[:i], m.campanias[i+1:]...)
+			return nil
+		}
+	}
+	return ErrCampaniaNoEncontrada
+}
+
+func (m *Memoria) TablaTiposCambio(ctx context.Context) ([]models.TipoCambio, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tipos := make([]models.TipoCambio, 0, len(m.tiposCambio))
+	for _, tipo := range m.tiposCambio {
+		tipos = append(tipos, tipo)
+	}
+	sort.Slice(tipos, func(i, j int) bool {
+		return tipos[i].Moneda < tipos[j].Moneda
+	})
+	return tipos, nil
+}
+
+func (m *Memoria) GuardarTipoCambio(ctx context.Context, tipo models.TipoCambio) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tiposCambio[tipo.Moneda] = tipo
+	m.historialCambios = append(m.historialCambios, tipo)
+	return nil
+}
+
+func (m *Memoria) HistorialTipoCambio(ctx context.Context, moneda string) ([]models.TipoCambio, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tipos := []models.TipoCambio{}
+	for i := len(m.historialCambios) - 1; i >= 0; i-- {
+		if m.historialCambios[i].Moneda == moneda {
+			tipos = append(tipos, m.historialCambios[i])
+		}
+	}
+	sort.SliceStable(tipos, func(i, j int) bool {
+		return tipos[i].ActualizadoEn.After(tipos[j].ActualizadoEn)
+	})
+	return tipos, nil
+}
+
+// tasas devuelve la tasa vigente de cada moneda cargada
+func (m *Memoria) tasas() models.TiposCambio {
+	tasas := models.TiposCambio{}
+	for moneda, tipo := range m.tiposCambio {
+		tasas[moneda] = tipo.Tasa
+	}
+	return tasas
+}
+
+// modificaCampo indica si la entrada del historial incluye un cambio del campo
+func modificaCampo(registro models.RegistroHistorial, campo string) bool {
+	for _, cambio := range registro.Cambios {
+		if cambio.Campo == campo {
+			return true
+		}
+	}
+	return false
+}
+
+// consultar devuelve los documentos calculados que cumplen la consulta, ordenados y paginados
+func (m *Memoria) consultar(consulta Consulta) ([]bson.M, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	filtro, err := normalizar(consulta.Filtro)
+	if err != nil {
+		return nil, err
+	}
+	desde, err := normalizar(consulta.Desde)
+	if err != nil {
+		return nil, err
+	}
+	todos, err := m.documentos()
+	if err != nil {
+		return nil, err
+	}
+
+	type resultado struct {
+		doc     bson.M
+		puntaje float64
+	}
+	resultados := []resultado{}
+	for _, doc := range todos {
+		puntaje, ok := puntajeTexto(doc, filtro)
+		if !ok {
+			continue
+		}
+		cumple, err := coincide(doc, filtro)
+		if err != nil {
+			return nil, err
+		}
+		sigue, err := coincide(doc, desde)
+		if err != nil {
+			return nil, err
+		}
+		if cumple && sigue {
+			resultados = append(resultados, resultado{doc: doc, puntaje: puntaje})
+		}
+	}
+
+	sort.SliceStable(resultados, func(i, j int) bool {
+		if consulta.Relevancia && resultados[i].puntaje != resultados[j].puntaje {
+			return resultados[i].puntaje > resultados[j].puntaje
+		}
+		return compararDocumentos(resultados[i].doc, resultados[j].doc, consulta.Orden) < 0
+	})
+
+	if consulta.Saltar > 0 {
+		resultados = resultados[min(consulta.Saltar, int64(len(resultados))):]
+	}
+	if consulta.Limite > 0 && int64(len(resultados)) > consulta.Limite {
+		resultados = resultados[:consulta.Limite]
+	}
+
+	docs := make([]bson.M, 0, len(resultados))
+	for _, r := range resultados {
+		docs = append(docs, r.doc)
+	}
+	return docs, nil
+}
+
+// documentos devuelve cada auto como documento con el precio efectivo y
+// precio_normalizado calculados, igual que las etapas de agregación del catálogo
+func (m *Memoria) documentos() ([]bson.M, error) {
+	now := time.Now()
+	tasas := m.tasas()
+	docs := make([]bson.M, 0, len(m.autos))
+	for _, stockID := range m.stockIDs() {
+		auto, err := m.leer(stockID)
+		if err != nil {
+			return nil, err
+		}
+		auto.AplicarPrecioEfectivo(now)
+
+		doc, err := aDocumento(auto)
+		if err != nil {
+			return nil, err
+		}
+		tasa, ok := tasas.Tasa(auto.Moneda)
+		if !ok {
+			tasa = 1
+		}
+		doc["precio_normalizado"] = auto.Precio / tasa
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// stockIDs devuelve en orden los stock_id de los autos que no están en la papelera
+func (m *Memoria) stockIDs() []string {
+	stockIDs := make([]string, 0, len(m.autos))
+	for stockID, data := range m.autos {
+		if _, err := data.LookupErr("deleted_at"); err == nil {
+			continue
+		}
+		stockIDs = append(stockIDs, stockID)
+	}
+	sort.Strings(stockIDs)
+	return stockIDs
+}
+
+// leer decodifica el auto con el stock_id indicado; los autos en la papelera no se encuentran
+func (m *Memoria) leer(stockID string) (models.Auto, error) {
+	auto, err := m.leerGuardado(stockID)
+	if err == nil && auto.DeletedAt != nil {
+		return models.Auto{}, ErrNoEncontrado
+	}
+	return auto, err
+}
+
+// leerGuardado decodifica el auto guardado con el stock_id indicado, esté o no en la papelera
+func (m *Memoria) leerGuardado(stockID string) (models.Auto, error) {
+	var auto models.Auto
+	data, ok := m.autos[stockID]
+	if !ok {
+		return auto, ErrNoEncontrado
+	}
+	err := bson.Unmarshal(data, &auto)
+	return auto, err
+}
+
+// guardar serializa el auto y lo guarda con su stock_id
+func (m *Memoria) guardar(auto models.Auto) error {
+	data, err := bson.Marshal(auto)
+	if err != nil {
+		return err
+	}
+	m.autos[auto.StockID] = data
+	return nil
+}
+
+// errSinCambios indica a modificar que no hay que guardar el auto
+var errSinCambios = errors.New("sin cambios")
+
+// modificar lee el auto, le aplica cambio y lo guarda con la revisión siguiente si cambio
+// no devuelve error
+func (m *Memoria) modificar(stockID string, cambio func(auto *models.Auto) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auto, err := m.leer(stockID)
+	if err != nil {
+		return err
+	}
+	if err := cambio(&auto); err != nil {
+		return err
+	}
+	auto.Revision++
+	return m.guardar(auto)
+}
+
+// copiar copia un valor en otro pasando por BSON
+func copiar(desde interface{}, hacia interface{}) error {
+	data, err := bson.Marshal(desde)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, hacia)
+}
+
+// aDocumento convierte un valor a su representación BSON como mapa
+func aDocumento(v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if err := copiar(v, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,384 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// pesosTexto son los campos del índice de texto con sus pesos, igual que en EnsureIndexes
+var pesosTexto = []struct {
+	campo string
+	peso  float64
+}{
+	{"marca", 10},
+	{"modelo", 10},
+	{"version", 5},
+	{"equipamiento_destacado", 3},
+	{"caracteristicas_texto", 1},
+}
+
+// sinAcentos quita los acentos como el índice de texto versión 3, que no distingue diacríticos
+var sinAcentos = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// normalizar pasa el filtro por BSON para que sus valores tengan los mismos tipos que
+// los documentos guardados (fechas como primitive.DateTime, listas como primitive.A)
+func normalizar(filtro bson.M) (bson.M, error) {
+	if len(filtro) == 0 {
+		return bson.M{}, nil
+	}
+	return aDocumento(filtro)
+}
+
+// coincide indica si el documento cumple el filtro. $text no se evalúa acá sino en
+// puntajeTexto. Devuelve error si el filtro usa un operador que Memoria no interpreta.
+func coincide(doc bson.M, filtro bson.M) (bool, error) {
+	for clave, cond := range filtro {
+		switch clave {
+		case "$text":
+			continue
+		case "$or", "$and", "$nor":
+			subfiltros, _ := comoLista(cond)
+			algunos := 0
+			for _, sub := range subfiltros {
+				subfiltro, ok := comoDocumento(sub)
+				if !ok {
+					continue
+				}
+				cumple, err := coincide(doc, subfiltro)
+				if err != nil {
+					return false, err
+				}
+				if cumple {
+					algunos++
+				}
+			}
+			if (clave == "$or" && algunos == 0) || (clave == "$and" && algunos < len(subfiltros)) || (clave == "$nor" && algunos > 0) {
+				return false, nil
+			}
+		default:
+			valor, existe := valorCampo(doc, clave)
+			cumple, err := coincideCampo(valor, existe, cond)
+			if err != nil || !cumple {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+// coincideCampo evalúa la condición de un campo: un documento de operadores, una
+// expresión regular o un valor que debe ser igual
+func coincideCampo(valor interface{}, existe bool, cond interface{}) (bool, error) {
+	operadores, ok := comoDocumento(cond)
+	if !ok || !esDocumentoDeOperadores(operadores) {
+		return igualOContiene(valor, cond), nil
+	}
+
+	for op, arg := range operadores {
+		var cumple bool
+		switch op {
+		case "$eq":
+			cumple = igualOContiene(valor, arg)
+		case "$ne":
+			cumple = !igualOContiene(valor, arg)
+		case "$in":
+			cumple = enLista(valor, arg)
+		case "$nin":
+			cumple = !enLista(valor, arg)
+		case "$gt", "$gte", "$lt", "$lte":
+			c, comparables := comparar(valor, arg)
+			cumple = existe && comparables && ((op == "$gt" && c > 0) || (op == "$gte" && c >= 0) || (op == "$lt" && c < 0) || (op == "$lte" && c <= 0))
+		case "$exists":
+			quiere, _ := arg.(bool)
+			cumple = existe == quiere
+		default:
+			return false, fmt.Errorf("repository.Memoria no soporta el operador %s", op)
+		}
+		if !cumple {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// esDocumentoDeOperadores indica si todas las claves del documento son operadores
+func esDocumentoDeOperadores(doc bson.M) bool {
+	if len(doc) == 0 {
+		return false
+	}
+	for clave := range doc {
+		if !strings.HasPrefix(clave, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// enLista indica si el valor, o alguno de sus elementos, está en la lista de $in
+func enLista(valor interface{}, arg interface{}) bool {
+	lista, _ := comoLista(arg)
+	for _, item := range lista {
+		if igualOContiene(valor, item) {
+			return true
+		}
+	}
+	return false
+}
+
+// igualOContiene compara el valor con cond. Si cond es una expresión regular la evalúa
+// sobre el texto, y si el valor es una lista alcanza con que coincida un elemento.
+func igualOContiene(valor interface{}, cond interface{}) bool {
+	if lista, ok := comoLista(valor); ok {
+		for _, elemento := range lista {
+			if igualOContiene(elemento, cond) {
+				return true
+			}
+		}
+		if _, esLista := comoLista(cond); !esLista {
+			return false
+		}
+	}
+	if regex, ok := cond.(primitive.Regex); ok {
+		texto, esTexto := valor.(string)
+		return esTexto && coincideRegex(texto, regex)
+	}
+	if c, ok := comparar(valor, cond); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(valor, cond)
+}
+
+// coincideRegex evalúa una expresión regular de MongoDB con sus opciones
+func coincideRegex(texto string, regex primitive.Regex) bool {
+	patron := regex.Pattern
+	if strings.Contains(regex.Options, "i") {
+		patron = "(?i)" + patron
+	}
+	re, err := regexp.Compile(patron)
+	return err == nil && re.MatchString(texto)
+}
+
+// comparar compara dos valores del mismo tipo BSON. Devuelve false si no son comparables.
+func comparar(a interface{}, b interface{}) (int, bool) {
+	if x, ok := aNumero(a); ok {
+		y, ok := aNumero(b)
+		if !ok {
+			return 0, false
+		}
+		return compararFloat(x, y), true
+	}
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(x, y), true
+	case primitive.DateTime:
+		y, ok := b.(primitive.DateTime)
+		if !ok {
+			return 0, false
+		}
+		return compararFloat(float64(x), float64(y)), true
+	case bool:
+		y, ok := b.(bool)
+		if !ok || x == y {
+			return 0, ok
+		}
+		if !x {
+			return -1, true
+		}
+		return 1, true
+	case nil:
+		if b == nil {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+func compararFloat(x float64, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// aNumero convierte un número decodificado de BSON a float64
+func aNumero(valor interface{}) (float64, bool) {
+	switch v := valor.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// ordenTipo es la posición del tipo en el orden de tipos de MongoDB
+func ordenTipo(valor interface{}, existe bool) int {
+	if !existe || valor == nil {
+		return 0
+	}
+	if _, ok := aNumero(valor); ok {
+		return 1
+	}
+	switch valor.(type) {
+	case string:
+		return 2
+	case bson.M, bson.D:
+		return 3
+	case primitive.A:
+		return 4
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	default:
+		return 10
+	}
+}
+
+// compararOrden compara dos valores para ordenarlos como lo hace MongoDB, primero por
+// tipo y después por valor
+func compararOrden(a interface{}, existeA bool, b interface{}, existeB bool) int {
+	tipoA, tipoB := ordenTipo(a, existeA), ordenTipo(b, existeB)
+	if tipoA != tipoB {
+		return tipoA - tipoB
+	}
+	c, _ := comparar(a, b)
+	return c
+}
+
+// compararDocumentos compara dos documentos según el orden indicado
+func compararDocumentos(a bson.M, b bson.M, orden bson.D) int {
+	for _, e := range orden {
+		direccion, ok := aNumero(e.Value)
+		if !ok {
+			continue
+		}
+		valorA, existeA := valorCampo(a, e.Key)
+		valorB, existeB := valorCampo(b, e.Key)
+		if c := compararOrden(valorA, existeA, valorB, existeB); c != 0 {
+			if direccion < 0 {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// indiceRango devuelve el índice del rango [limites[i], limites[i+1]) que contiene al
+// valor, o -1 si no está en ningún rango
+func indiceRango(valor interface{}, limites []float64) int {
+	v, ok := aNumero(valor)
+	if !ok {
+		return -1
+	}
+	for i := 0; i < len(limites)-1; i++ {
+		if v >= limites[i] && v < limites[i+1] {
+			return i
+		}
+	}
+	return -1
+}
+
+// puntajeTexto evalúa el $text del filtro sobre el documento. Devuelve true si no hay
+// $text o si algún término aparece al comienzo de una palabra de los campos de texto,
+// sin distinguir mayúsculas ni acentos; el puntaje suma los pesos de cada coincidencia.
+func puntajeTexto(doc bson.M, filtro bson.M) (float64, bool) {
+	text, ok := comoDocumento(filtro["$text"])
+	if !ok {
+		return 0, true
+	}
+	busqueda, _ := text["$search"].(string)
+	terminos := palabras(busqueda)
+
+	puntaje := 0.0
+	for _, p := range pesosTexto {
+		valor, _ := valorCampo(doc, p.campo)
+		textos := []interface{}{valor}
+		if lista, ok := comoLista(valor); ok {
+			textos = lista
+		}
+		for _, t := range textos {
+			texto, _ := t.(string)
+			for _, palabra := range palabras(texto) {
+				for _, termino := range terminos {
+					if strings.HasPrefix(palabra, termino) {
+						puntaje += p.peso
+					}
+				}
+			}
+		}
+	}
+	return puntaje, puntaje > 0
+}
+
+// palabras separa el texto en palabras en minúsculas y sin acentos
+func palabras(texto string) []string {
+	texto = sinAcentos.Replace(strings.ToLower(texto))
+	return strings.FieldsFunc(texto, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// valorCampo devuelve el valor de un campo del documento, que puede tener puntos para
+// acceder a subdocumentos
+func valorCampo(doc bson.M, campo string) (interface{}, bool) {
+	var valor interface{} = doc
+	for _, parte := range strings.Split(campo, ".") {
+		actual, ok := comoDocumento(valor)
+		if !ok {
+			return nil, false
+		}
+		if valor, ok = actual[parte]; !ok {
+			return nil, false
+		}
+	}
+	return valor, true
+}
+
+// comoDocumento convierte un documento decodificado de BSON a bson.M
+func comoDocumento(valor interface{}) (bson.M, bool) {
+	switch v := valor.(type) {
+	case bson.M:
+		return v, true
+	case bson.D:
+		doc := bson.M{}
+		for _, e := range v {
+			doc[e.Key] = e.Value
+		}
+		return doc, true
+	default:
+		return nil, false
+	}
+}
+
+// comoLista convierte una lista decodificada de BSON a []interface{}
+func comoLista(valor interface{}) ([]interface{}, bool) {
+	switch v := valor.(type) {
+	case primitive.A:
+		return v, true
+	case []interface{}:
+		return v, true
+	default:
+		return nil, false
+	}
+}
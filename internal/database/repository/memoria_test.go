@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMemoriaContrato(t *testing.T) {
+	probarContrato(t, func(t *testing.T) AutoRepository {
+		return NewMemoria()
+	})
+}
+
+func TestMemoriaOperadorNoSoportado(t *testing.T) {
+	ctx := context.Background()
+	repo := NewMemoria()
+	if err := repo.Create(ctx, autoPrueba("T0001", "Toyota", 10000)); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Un operador que Memoria no interpreta es un error, no un panic
+	filtro := bson.M{"$or": bson.A{bson.M{"precio": bson.M{"$mod": bson.A{2, 0}}}}}
+	if _, err := repo.Filter(ctx, Consulta{Filtro: filtro}); err == nil {
+		t.Error("Filter con $mod: se esperaba un error")
+	}
+	if _, err := repo.Count(ctx, Consulta{Filtro: filtro}); err == nil {
+		t.Error("Count con $mod: se esperaba un error")
+	}
+	if _, err := repo.Facets(ctx, ConsultaFacetas{Filtro: filtro, Categoricas: []string{"marca"}}); err == nil {
+		t.Error("Facets con $mod: se esperaba un error")
+	}
+}
@@ -0,0 +1,709 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoRepository struct {
+	db database.Service
+}
+
+// NewMongo crea el repositorio de autos sobre las colecciones de MongoDB
+func NewMongo(db database.Service) AutoRepository {
+	return &mongoRepository{db: db}
+}
+
+func (m *mongoRepository) autos() *mongo.Collection {
+	return m.db.Collection("autos")
+}
+
+func (m *mongoRepository) FindByStockID(ctx context.Context, stockID string) (models.Auto, error) {
+	var auto models.Auto
+	err := m.autos().FindOne(ctx, soloActivos(bson.M{"stock_id": stockID})).Decode(&auto)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return auto, ErrNoEncontrado
+	}
+	return auto, err
+}
+
+func (m *mongoRepository) FindAll(ctx context.Context) ([]models.Auto, error) {
+	opts := options.Find().SetSort(bson.M{"stock_id": 1})
+	cursor, err := m.autos().Find(ctx, soloActivos(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	autos := []models.Auto{}
+	if err := cursor.All(ctx, &autos); err != nil {
+		return nil, err
+	}
+	return autos, nil
+}
+
+func (m *mongoRepository) Filter(ctx context.Context, consulta Consulta) ([]models.Auto, error) {
+	autos := []models.Auto{}
+	if err := m.aggregate(ctx, pipelineConsulta(consulta, nil), &autos); err != nil {
+		return nil, err
+	}
+	return autos, nil
+}
+
+func (m *mongoRepository) FilterPublic(ctx context.Context, consulta Consulta) ([]models.AutoPublico, error) {
+	autos := []models.AutoPublico{}
+	if err := m.aggregate(ctx, pipelineConsulta(consulta, models.ProyeccionPublica()), &autos); err != nil {
+		return nil, err
+	}
+	return autos, nil
+}
+
+func (m *mongoRepository) Count(ctx context.Context, consulta Consulta) (int64, error) {
+	pipeline := pipelineCatalogo(consulta.Filtro)
+	if len(consulta.Desde) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: consulta.Desde}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$count", Value: "total"}})
+
+	var result []struct {
+		Total int64 `bson:"total"`
+	}
+	if err := m.aggregate(ctx, pipeline, &result); err != nil {
+		return 0, err
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Total, nil
+}
+
+func (m *mongoRepository) Facets(ctx context.Context, consulta ConsultaFacetas) (ResultadoFacetas, error) {
+	base := sinCampo(consulta.Filtro, "$text")
+
+	facets := bson.M{
+		"total": bson.A{
+			bson.M{"$match": base},
+			bson.M{"$count": "cantidad"},
+		},
+	}
+	for _, campo := range consulta.Categoricas {
+		facets[campo] = bson.A{
+			bson.M{"$match": sinCampo(base, campo)},
+			bson.M{"$group": bson.M{"_id": "$" + campo, "cantidad": bson.M{"$sum": 1}}},
+			bson.M{"$match": bson.M{"_id": bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$sort": bson.D{{Key: "cantidad", Value: -1}, {Key: "_id", Value: 1}}},
+		}
+	}
+	for _, rango := range consulta.Rangos {
+		facets[rango.Nombre] = bson.A{
+			bson.M{"$match": sinCampo(base, rango.Campo)},
+			bson.M{"$bucket": bson.M{
+				"groupBy":    "$" + rango.Campo,
+				"boundaries": rango.Limites,
+				"default":    FacetaOtros,
+				"output":     bson.M{"cantidad": bson.M{"$sum": 1}},
+			}},
+		}
+	}
+
+	// $text solo se permite en la primera etapa, no dentro de $facet. Después se calcula
+	// el precio efectivo para que la faceta de precio use el precio con descuento,
+	// expresado en la moneda de referencia.
+	primera := soloActivos(bson.M{})
+	if text, ok := consulta.Filtro["$text"]; ok {
+		primera["$text"] = text
+	}
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: primera}}}
+	pipeline = append(pipeline, models.EtapasPrecioEfectivo()...)
+	pipeline = append(pipeline, models.EtapasPrecioNormalizado()...)
+	pipeline = append(pipeline, bson.D{{Key: "$facet", Value: facets}})
+
+	var results []bson.Raw
+	if err := m.aggregate(ctx, pipeline, &results); err != nil {
+		return ResultadoFacetas{}, err
+	}
+	if len(results) == 0 {
+		return ResultadoFacetas{}, errors.New("la agregación de facetas no devolvió resultados")
+	}
+
+	resultado := ResultadoFacetas{Conteo: map[string][]ConteoFaceta{}}
+	var total []struct {
+		Cantidad int `bson:"cantidad"`
+	}
+	if err := results[0].Lookup("total").Unmarshal(&total); err != nil {
+		return ResultadoFacetas{}, err
+	}
+	if len(total) > 0 {
+		resultado.Total = total[0].Cantidad
+	}
+	for nombre := range facets {
+		if nombre == "total" {
+			continue
+		}
+		conteo := []ConteoFaceta{}
+		if err := results[0].Lookup(nombre).Unmarshal(&conteo); err != nil {
+			return ResultadoFacetas{}, err
+		}
+		resultado.Conteo[nombre] = conteo
+	}
+	return resultado, nil
+}
+
+func (m *mongoRepository) Monedas(ctx context.Context) ([]string, error) {
+	valores, err := m.autos().Distinct(ctx, "moneda", soloActivos(bson.M{}))
+	if err != nil {
+		return nil, err
+	}
+	monedas := []string{}
+	for _, valor := range valores {
+		if moneda, ok := valor.(string); ok {
+			monedas = append(monedas, moneda)
+		}
+	}
+	return monedas, nil
+}
+
+func (m *mongoRepository) TiposCambio(ctx context.Context) (models.TiposCambio, error) {
+	tipos, err := m.buscarTiposCambio(ctx, "tipos_cambio", bson.M{}, options.Find())
+	if err != nil {
+		return nil, err
+	}
+
+	tasas := models.TiposCambio{}
+	for _, tipo := range tipos {
+		tasas[tipo.Moneda] = tipo.Tasa
+	}
+	return tasas, nil
+}
+
+func (m *mongoRepository) Estadisticas(ctx context.Context, now time.Time) (Estadisticas, error) {
+	// Un estado vacío o ausente no es mayor que "" y cuenta como disponible
+	estado := bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{"$estado", ""}}, "$estado", models.EstadoDisponible}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: soloActivos(bson.M{})}},
+		{{Key: "$facet", Value: bson.M{
+			"estado": bson.A{
+				bson.M{"$group": bson.M{"_id": estado, "cantidad": bson.M{"$sum": 1}}},
+			},
+			"sucursal": bson.A{
+				bson.M{"$group": bson.M{"_id": "$sucursal", "cantidad": bson.M{"$sum": 1}}},
+			},
+			"destacados": bson.A{
+				bson.M{"$match": bson.M{"featured": true}},
+				bson.M{"$count": "cantidad"},
+			},
+			"reservas": bson.A{
+				bson.M{"$unwind": "$reservas"},
+				bson.M{"$match": bson.M{"reservas.fechahora": bson.M{"$gt": now}}},
+				bson.M{"$count": "cantidad"},
+			},
+			"dias": bson.A{
+				bson.M{"$match": bson.M{"estado": bson.M{"$ne": models.EstadoVendido}}},
+				bson.M{"$group": bson.M{
+					"_id":      nil,
+					"promedio": bson.M{"$avg": bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, "$created_at"}}, float64(24 * time.Hour / time.Millisecond)}}},
+				}},
+			},
+		}}},
+	}
+
+	var resultados []struct {
+		Estado     []ConteoFaceta `bson:"estado"`
+		Sucursal   []ConteoFaceta `bson:"sucursal"`
+		Destacados []struct {
+			Cantidad int64 `bson:"cantidad"`
+		} `bson:"destacados"`
+		Reservas []struct {
+			Cantidad int64 `bson:"cantidad"`
+		} `bson:"reservas"`
+		Dias []struct {
+			Promedio float64 `bson:"promedio"`
+		} `bson:"dias"`
+	}
+	if err := m.aggregate(ctx, pipeline, &resultados); err != nil {
+		return Estadisticas{}, err
+	}
+
+	estadisticas := Estadisticas{PorEstado: map[string]int64{}, PorSucursal: map[string]int64{}}
+	if len(resultados) == 0 {
+		return estadisticas, nil
+	}
+	resultado := resultados[0]
+	for _, conteo := range resultado.Estado {
+		valor, _ := conteo.Valor.(string)
+		estadisticas.PorEstado[valor] += int64(conteo.Cantidad)
+	}
+	for _, conteo := range resultado.Sucursal {
+		valor, _ := conteo.Valor.(string)
+		estadisticas.PorSucursal[valor] += int64(conteo.Cantidad)
+	}
+	if len(resultado.Destacados) > 0 {
+		estadisticas.Destacados = resultado.Destacados[0].Cantidad
+	}
+	if len(resultado.Reservas) > 0 {
+		estadisticas.ReservasActivas = resultado.Reservas[0].Cantidad
+	}
+	if len(resultado.Dias) > 0 {
+		estadisticas.DiasEnStockPromedio = resultado.Dias[0].Promedio
+	}
+	return estadisticas, nil
+}
+
+func (m *mongoRepository) NextStockIDNumber(ctx context.Context, prefijo string) (int64, error) {
+	return database.SiguienteSecuencia(ctx, m.db, database.SecuenciaStockID(prefijo))
+}
+
+func (m *mongoRepository) Create(ctx context.Context, auto models.Auto) error {
+	auto.Revision = 1
+	_, err := m.autos().InsertOne(ctx, auto)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrStockIDDuplicado
+	}
+	return err
+}
+
+func (m *mongoRepository) Update(ctx context.Context, auto models.Auto) error {
+	revision := auto.Revision
+	// La revisión solo cambia con el $inc de updateOneSi
+	auto.Revision = 0
+	return m.updateOneSi(ctx, auto.StockID, condicionRevision(revision), bson.M{"$set": auto}, ErrRevisionCambiada)
+}
+
+func (m *mongoRepository) Delete(ctx context.Context, stockID string, revision int64, fecha time.Time) error {
+	update := bson.M{"$set": bson.M{"deleted_at": fecha}}
+	return m.updateOneSi(ctx, stockID, condicionRevision(revision), update, ErrRevisionCambiada)
+}
+
+func (m *mongoRepository) Papelera(ctx context.Context) ([]models.Auto, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}, {Key: "stock_id", Value: 1}})
+	cursor, err := m.autos().Find(ctx, bson.M{"deleted_at": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	autos := []models.Auto{}
+	if err := cursor.All(ctx, &autos); err != nil {
+		return nil, err
+	}
+	return autos, nil
+}
+
+func (m *mongoRepository) Restaurar(ctx context.Context, stockID string) (models.Auto, error) {
+	var auto models.Auto
+	filter := bson.M{"stock_id": stockID, "deleted_at": bson.M{"$exists": true}}
+	update := bson.M{
+		"$unset": bson.M{"deleted_at": ""},
+		"$inc":   bson.M{"revision": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := m.autos().FindOneAndUpdate(ctx, filter, update, opts).Decode(&auto)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return auto, ErrNoEncontrado
+	}
+	return auto, err
+}
+
+func (m *mongoRepository) Purgar(ctx context.Context, antesDe time.Time) (int64, error) {
+	result, err := m.autos().DeleteMany(ctx, bson.M{"deleted_at": bson.M{"$lt": antesDe}})
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (m *mongoRepository) SetFeatured(ctx context.Context, stockID string, featured bool) error {
+	return m.updateOne(ctx, stockID, bson.M{"$set": bson.M{"featured": featured}})
+}
+
+func (m *mongoRepository) AgregarReserva(ctx context.Context, stockID string, reserva models.Reserva) (models.Auto, error) {
+	var auto models.Auto
+
+	// $push falla sobre un campo null, que es como se guardan los autos sin reservas
+	filter := soloActivos(bson.M{"stock_id": stockID, "reservas": nil})
+	_, err := m.autos().UpdateOne(ctx, filter, bson.M{"$set": bson.M{"reservas": bson.A{}}})
+	if err != nil {
+		return auto, err
+	}
+
+	filter = soloActivos(bson.M{
+		"stock_id": stockID,
+		"reservas": bson.M{"$not": bson.M{"$elemMatch": bson.M{"nombre": reserva.Nombre, "apellido": reserva.Apellido}}},
+	})
+	update := bson.M{
+		"$push": bson.M{"reservas": reserva},
+		"$inc":  bson.M{"revision": 1},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = m.autos().FindOneAndUpdate(ctx, filter, update, opts).Decode(&auto)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return auto, m.noCumple(ctx, stockID, ErrReservaDuplicada)
+	}
+	return auto, err
+}
+
+func (m *mongoRepository) EditarReserva(ctx context.Context, stockID string, reserva models.Reserva) error {
+	condicion := bson.M{"reservas.id": reserva.ID}
+	return m.updateOneSi(ctx, stockID, condicion, bson.M{"$set": bson.M{"reservas.$": reserva}}, ErrReservaNoEncontrada)
+}
+
+func (m *mongoRepository) EliminarReserva(ctx context.Context, stockID string, reservaID string) error {
+	condicion := bson.M{"reservas.id": reservaID}
+	update := bson.M{"$pull": bson.M{"reservas": bson.M{"id": reservaID}}}
+	return m.updateOneSi(ctx, stockID, condicion, update, ErrReservaNoEncontrada)
+}
+
+func (m *mongoRepository) UpdatePrecios(ctx context.Context, auto models.Auto) error {
+	set, unset := camposPrecios(auto)
+	if auto.Promocion != nil {
+		set["promocion"] = auto.Promocion
+	} else {
+		unset["promocion"] = ""
+	}
+	return m.updateOneSi(ctx, auto.StockID, condicionRevision(auto.Revision), actualizacion(set, unset), ErrRevisionCambiada)
+}
+
+// camposPrecios devuelve los campos a guardar y a borrar para guardar los precios y la
+// serie de precios del auto
+func camposPrecios(auto models.Auto) (bson.M, bson.M) {
+	set := bson.M{
+		"precio":            auto.Precio,
+		"precio_lista":      auto.PrecioLista,
+		"descuento":         auto.Descuento,
+		"bajo_de_precio":    auto.BajoDePrecio,
+		"historial_precios": auto.HistorialPrecios,
+		"updated_at":        auto.UpdatedAt,
+	}
+	unset := bson.M{}
+	if auto.PrecioAnterior > 0 {
+		set["precio_anterior"] = auto.PrecioAnterior
+	} else {
+		unset["precio_anterior"] = ""
+	}
+	if !auto.BajoDePrecioEn.IsZero() {
+		set["bajo_de_precio_en"] = auto.BajoDePrecioEn
+	} else {
+		unset["bajo_de_precio_en"] = ""
+	}
+	return set, unset
+}
+
+// actualizacion arma el update con los campos a guardar y a borrar; MongoDB rechaza un
+// $unset vacío
+func actualizacion(set bson.M, unset bson.M) bson.M {
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func (m *mongoRepository) AsignarCampania(ctx context.Context, stockIDs []string, campania models.PromocionCampania, fecha time.Time) error {
+	for _, stockID := range stockIDs {
+		promocion := campania
+		anterior, err := m.cambiarCampania(ctx, stockID, &promocion, fecha)
+		if errors.Is(err, ErrNoEncontrado) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if anterior != nil && anterior.CampaniaID != campania.CampaniaID {
+			filter := bson.M{"id": anterior.CampaniaID}
+			if _, err := m.db.Collection("campanias").UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"stock_ids": stockID}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mongoRepository) QuitarCampania(ctx context.Context, campaniaID string, fecha time.Time) (int64, error) {
+	valores, err := m.autos().Distinct(ctx, "stock_id", soloActivos(bson.M{"campania.campania_id": campaniaID}))
+	if err != nil {
+		return 0, err
+	}
+
+	var modificados int64
+	for _, valor := range valores {
+		stockID, _ := valor.(string)
+		_, err := m.cambiarCampania(ctx, stockID, nil, fecha)
+		if errors.Is(err, ErrNoEncontrado) {
+			continue
+		}
+		if err != nil {
+			return modificados, err
+		}
+		modificados++
+	}
+	return modificados, nil
+}
+
+// maxIntentosCampania es la cantidad de veces que se reintenta cambiar la campaña de un
+// auto que otra solicitud modificó entre la lectura y la escritura
+const maxIntentosCampania = 3
+
+// cambiarCampania asigna o quita la campaña del auto junto con sus precios, sobre la
+// revisión leída, y devuelve la campaña que tenía
+func (m *mongoRepository) cambiarCampania(ctx context.Context, stockID string, campania *models.PromocionCampania, fecha time.Time) (*models.PromocionCampania, error) {
+	var err error
+	for intento := 0; intento < maxIntentosCampania; intento++ {
+		var auto models.Auto
+		auto, err = m.FindByStockID(ctx, stockID)
+		if err != nil {
+			return nil, err
+		}
+		anterior := auto.Campania
+		auto.CambiarCampania(campania, fecha)
+
+		set, unset := camposPrecios(auto)
+		if campania != nil {
+			set["campania"] = campania
+		} else {
+			unset["campania"] = ""
+		}
+		err = m.updateOneSi(ctx, stockID, condicionRevision(auto.Revision), actualizacion(set, unset), ErrRevisionCambiada)
+		if !errors.Is(err, ErrRevisionCambiada) {
+			return anterior, err
+		}
+	}
+	return nil, err
+}
+
+func (m *mongoRepository) CambiarEstado(ctx context.Context, stockID string, revision int64, transicion models.TransicionEstado, info models.InfoEstado) error {
+	set := bson.M{
+		"estado":                transicion.Hacia,
+		"estado_actualizado_en": transicion.Fecha,
+		"updated_at":            transicion.Fecha,
+	}
+	unset := bson.M{}
+	for _, estado := range models.EstadosValidos {
+		campo := models.CampoInfoEstado(estado)
+		if campo == "" {
+			continue
+		}
+		if estado == transicion.Hacia {
+			set[campo] = info.Valor(estado)
+		} else {
+			unset[campo] = ""
+		}
+	}
+	update := bson.M{
+		"$set":   set,
+		"$unset": unset,
+		"$push":  bson.M{"historial_estados": transicion},
+	}
+	return m.updateOneSi(ctx, stockID, condicionRevision(revision), update, ErrRevisionCambiada)
+}
+
+func (m *mongoRepository) RegistrarHistorial(ctx context.Context, registro models.RegistroHistorial) error {
+	_, err := m.db.Collection("auto_history").InsertOne(ctx, registro)
+	return err
+}
+
+func (m *mongoRepository) Historial(ctx context.Context, stockID string, campo string) ([]models.RegistroHistorial, error) {
+	filter := bson.M{"stock_id": stockID}
+	if campo != "" {
+		filter["cambios.campo"] = campo
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "fecha", Value: -1}})
+	cursor, err := m.db.Collection("auto_history").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	registros := []models.RegistroHistorial{}
+	if err := cursor.All(ctx, &registros); err != nil {
+		return nil, err
+	}
+	return registros, nil
+}
+
+// updateOne aplica el update al auto con el stock_id indicado, o devuelve ErrNoEncontrado
+func (m *mongoRepository) updateOne(ctx context.Context, stockID string, update bson.M) error {
+	return m.updateOneSi(ctx, stockID, bson.M{}, update, ErrNoEncontrado)
+}
+
+// updateOneSi aplica el update e incrementa la revisión del auto con el stock_id indicado
+// si además cumple la condición. Si el auto existe pero no la cumple devuelve siNoCumple.
+func (m *mongoRepository) updateOneSi(ctx context.Context, stockID string, condicion bson.M, update bson.M, siNoCumple error) error {
+	filter := soloActivos(bson.M{"stock_id": stockID})
+	for campo, valor := range condicion {
+		filter[campo] = valor
+	}
+	update["$inc"] = bson.M{"revision": 1}
+
+	result, err := m.autos().UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return m.noCumple(ctx, stockID, siNoCumple)
+	}
+	return nil
+}
+
+// noCumple explica por qué una escritura condicional no encontró el auto: ErrNoEncontrado
+// si no existe, o err si existe pero no cumplía la condición
+func (m *mongoRepository) noCumple(ctx context.Context, stockID string, err error) error {
+	existe, errCount := m.autos().CountDocuments(ctx, soloActivos(bson.M{"stock_id": stockID}))
+	if errCount != nil {
+		return errCount
+	}
+	if existe == 0 {
+		return ErrNoEncontrado
+	}
+	return err
+}
+
+// soloActivos agrega al filtro la condición de que el auto no esté en la papelera
+func soloActivos(filter bson.M) bson.M {
+	filter["deleted_at"] = bson.M{"$exists": false}
+	return filter
+}
+
+// condicionRevision es el filtro de un auto que sigue en la revisión indicada. La revisión
+// 0 también corresponde a los autos guardados sin el campo.
+func condicionRevision(revision int64) bson.M {
+	if revision == 0 {
+		return bson.M{"revision": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"revision": revision}
+}
+
+// aggregate ejecuta el pipeline sobre la colección de autos y decodifica todos los resultados
+func (m *mongoRepository) aggregate(ctx context.Context, pipeline mongo.Pipeline, results interface{}) error {
+	cursor, err := m.autos().Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// pipelineConsulta arma el pipeline completo de una consulta del catálogo. Con
+// proyeccion solo se leen esos campos.
+func pipelineConsulta(consulta Consulta, proyeccion bson.M) mongo.Pipeline {
+	pipeline := pipelineCatalogo(consulta.Filtro)
+	if len(consulta.Desde) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: consulta.Desde}})
+	}
+
+	orden := consulta.Orden
+	if consulta.Relevancia {
+		orden = append(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}, orden...)
+	}
+	if len(orden) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: orden}})
+	}
+	if consulta.Saltar > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: consulta.Saltar}})
+	}
+	if consulta.Limite > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: consulta.Limite}})
+	}
+	if proyeccion != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$project", Value: proyeccion}})
+	}
+	return pipeline
+}
+
+// pipelineCatalogo arma las etapas de agregación que filtran el catálogo calculando el
+// precio efectivo de cada auto y su valor en la moneda de referencia. Los filtros sobre
+// campos guardados van antes del cálculo para aprovechar los índices y los filtros sobre
+// precios van después. Los autos en la papelera nunca forman parte del catálogo.
+func pipelineCatalogo(filter bson.M) mongo.Pipeline {
+	antes := soloActivos(bson.M{})
+	despues := bson.M{}
+	for key, value := range filter {
+		if camposCalculados[key] {
+			despues[key] = value
+		} else {
+			antes[key] = value
+		}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: antes}}}
+	pipeline = append(pipeline, models.EtapasPrecioEfectivo()...)
+	pipeline = append(pipeline, models.EtapasPrecioNormalizado()...)
+	if len(despues) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: despues}})
+	}
+	return pipeline
+}
+
+func (m *mongoRepository) Campanias(ctx context.Context) ([]models.Campania, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "creado_en", Value: -1}})
+	cursor, err := m.db.Collection("campanias").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	campanias := []models.Campania{}
+	if err := cursor.All(ctx, &campanias); err != nil {
+		return nil, err
+	}
+	return campanias, nil
+}
+
+func (m *mongoRepository) CrearCampania(ctx context.Context, campania models.Campania) error {
+	_, err := m.db.Collection("campanias").InsertOne(ctx, campania)
+	return err
+}
+
+func (m *mongoRepository) EliminarCampania(ctx context.Context, campaniaID string) error {
+	result, err := m.db.Collection("campanias").DeleteOne(ctx, bson.M{"id": campaniaID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrCampaniaNoEncontrada
+	}
+	return nil
+}
+
+func (m *mongoRepository) TablaTiposCambio(ctx context.Context) ([]models.TipoCambio, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "moneda", Value: 1}})
+	return m.buscarTiposCambio(ctx, "tipos_cambio", bson.M{}, opts)
+}
+
+func (m *mongoRepository) GuardarTipoCambio(ctx context.Context, tipo models.TipoCambio) error {
+	opts := options.Replace().SetUpsert(true)
+	if _, err := m.db.Collection("tipos_cambio").ReplaceOne(ctx, bson.M{"moneda": tipo.Moneda}, tipo, opts); err != nil {
+		return err
+	}
+	// El historial guarda cada tasa cargada, incluida la vigente
+	_, err := m.db.Collection("tipos_cambio_historial").InsertOne(ctx, tipo)
+	return err
+}
+
+func (m *mongoRepository) HistorialTipoCambio(ctx context.Context, moneda string) ([]models.TipoCambio, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "actualizado_en", Value: -1}})
+	return m.buscarTiposCambio(ctx, "tipos_cambio_historial", bson.M{"moneda": moneda}, opts)
+}
+
+// buscarTiposCambio lee los tipos de cambio de la colección que cumplen el filtro
+func (m *mongoRepository) buscarTiposCambio(ctx context.Context, coleccion string, filter bson.M, opts *options.FindOptions) ([]models.TipoCambio, error) {
+	cursor, err := m.db.Collection(coleccion).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tipos := []models.TipoCambio{}
+	if err := cursor.All(ctx, &tipos); err != nil {
+		return nil, err
+	}
+	return tipos, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-gorilla-autos/internal/database"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+)
+
+// dockerDisponible indica si testcontainers puede usar Docker. Sin ningún socket de
+// Docker testcontainers entra en pánico en lugar de devolver un error.
+func dockerDisponible() (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	return err == nil && provider.Health(context.Background()) == nil
+}
+
+// TestMongoContrato corre el contrato contra un MongoDB en Docker. Se saltea con -short
+// o si no hay Docker disponible.
+func TestMongoContrato(t *testing.T) {
+	if testing.Short() {
+		t.Skip("test de integración con MongoDB")
+	}
+	if !dockerDisponible() {
+		t.Skip("Docker no está disponible")
+	}
+
+	ctx := context.Background()
+	container, err := mongodb.Run(ctx, "mongo:latest")
+	if err != nil {
+		t.Fatalf("no se pudo iniciar MongoDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Logf("no se pudo detener MongoDB: %v", err)
+		}
+	})
+
+	uri, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("ConnectionString: %v", err)
+	}
+	// Cada caso usa una base nueva con los índices de producción
+	bases := 0
+	probarContrato(t, func(t *testing.T) AutoRepository {
+		bases++
+		db, err := database.New(database.Config{URI: uri, Nombre: fmt.Sprintf("contrato_%d", bases), Reintentos: 1})
+		if err != nil {
+			t.Fatalf("database.New: %v", err)
+		}
+		t.Cleanup(func() { db.Close(context.Background()) })
+		if err := database.EnsureIndexes(ctx, db); err != nil {
+			t.Fatalf("EnsureIndexes: %v", err)
+		}
+		return NewMongo(db)
+	})
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	// ErrNoEncontrado indica que no existe un auto con el stock_id pedido
+	ErrNoEncontrado = errors.New("auto no encontrado")
+	// ErrStockIDDuplicado indica que ya existe un auto con el mismo stock_id
+	ErrStockIDDuplicado = errors.New("ya existe un auto con ese stock_id")
+	// ErrRevisionCambiada indica que el auto se modificó desde que se leyó su revisión
+	ErrRevisionCambiada = errors.New("el auto cambió mientras se procesaba la solicitud")
+	// ErrReservaNoEncontrada indica que el auto no tiene una reserva con el id pedido
+	ErrReservaNoEncontrada = errors.New("reserva no encontrada")
+	// ErrReservaDuplicada indica que el cliente ya tiene una reserva para el auto
+	ErrReservaDuplicada = errors.New("ya existe una reserva activa para este cliente y vehículo")
+	// ErrCampaniaNoEncontrada indica que no existe una campaña con el id pedido
+	ErrCampaniaNoEncontrada = errors.New("campaña no encontrada")
+)
+
+// AutoRepository es el acceso a los autos y a su historial de cambios. Los handlers lo
+// usan en lugar de la colección de MongoDB para poder probarse con NewMemoria.
+//
+// Los autos en la papelera (con deleted_at) no se leen ni se modifican: para todos los
+// métodos salvo los de la papelera es como si no existieran.
+//
+// Cada escritura incrementa la revisión del auto. Las escrituras que reciben una
+// revisión solo se aplican si el auto sigue en esa revisión, o devuelven
+// ErrRevisionCambiada; los autos guardados antes de existir el campo tienen revisión 0.
+type AutoRepository interface {
+	// FindByStockID devuelve el auto tal como está guardado, o ErrNoEncontrado
+	FindByStockID(ctx context.Context, stockID string) (models.Auto, error)
+	// FindAll devuelve todos los autos guardados ordenados por stock_id
+	FindAll(ctx context.Context) ([]models.Auto, error)
+
+	// Filter devuelve los autos del catálogo que cumplen la consulta, con el precio
+	// efectivo calculado
+	Filter(ctx context.Context, consulta Consulta) ([]models.Auto, error)
+	// FilterPublic es Filter leyendo solo los campos públicos de cada auto
+	FilterPublic(ctx context.Context, consulta Consulta) ([]models.AutoPublico, error)
+	// Count cuenta los autos que cumplen la consulta sin tener en cuenta orden ni páginas
+	Count(ctx context.Context, consulta Consulta) (int64, error)
+	// Facets cuenta los autos por valor y por rangos para las facetas del catálogo
+	Facets(ctx context.Context, consulta ConsultaFacetas) (ResultadoFacetas, error)
+	// Monedas devuelve las monedas distintas de los autos guardados
+	Monedas(ctx context.Context) ([]string, error)
+	// TiposCambio devuelve las tasas vigentes con las que se calcula precio_normalizado
+	TiposCambio(ctx context.Context) (models.TiposCambio, error)
+	// Estadisticas resume el inventario a la fecha indicada para las métricas del negocio
+	Estadisticas(ctx context.Context, now time.Time) (Estadisticas, error)
+
+	// NextStockIDNumber incrementa atómicamente la secuencia de stock_id del prefijo
+	NextStockIDNumber(ctx context.Context, prefijo string) (int64, error)
+	// Create guarda un auto nuevo con revisión 1, o devuelve ErrStockIDDuplicado
+	Create(ctx context.Context, auto models.Auto) error
+	// Update guarda todos los campos del auto con su stock_id si sigue en auto.Revision
+	Update(ctx context.Context, auto models.Auto) error
+	// Delete mueve el auto a la papelera con la fecha indicada si sigue en la revisión indicada
+	Delete(ctx context.Context, stockID string, revision int64, fecha time.Time) error
+	// Papelera devuelve los autos en la papelera del borrado más reciente al más antiguo
+	Papelera(ctx context.Context) ([]models.Auto, error)
+	// Restaurar saca el auto de la papelera y lo devuelve tal como estaba en ella, con
+	// deleted_at y la revisión anterior. Devuelve ErrNoEncontrado si no está en la papelera.
+	Restaurar(ctx context.Context, stockID string) (models.Auto, error)
+	// Purgar elimina definitivamente los autos que están en la papelera desde antes de la
+	// fecha indicada y devuelve cuántos eliminó
+	Purgar(ctx context.Context, antesDe time.Time) (int64, error)
+	// SetFeatured marca o desmarca el auto como destacado
+	SetFeatured(ctx context.Context, stockID string, featured bool) error
+	// AgregarReserva agrega la reserva al auto y lo devuelve actualizado. Si el cliente
+	// (nombre y apellido) ya tiene una reserva devuelve ErrReservaDuplicada.
+	AgregarReserva(ctx context.Context, stockID string, reserva models.Reserva) (models.Auto, error)
+	// EditarReserva reemplaza la reserva con el mismo id, o devuelve ErrReservaNoEncontrada
+	EditarReserva(ctx context.Context, stockID string, reserva models.Reserva) error
+	// EliminarReserva quita la reserva con el id indicado, o devuelve ErrReservaNoEncontrada
+	EliminarReserva(ctx context.Context, stockID string, reservaID string) error
+	// UpdatePrecios guarda los precios, la promoción y la serie de precios del auto si
+	// sigue en auto.Revision; si no, devuelve ErrRevisionCambiada
+	UpdatePrecios(ctx context.Context, auto models.Auto) error
+	// AsignarCampania guarda la promoción de una campaña en los autos indicados y agrega
+	// un punto a la serie de precios de los que cambian de precio. Si un auto tenía otra
+	// campaña, lo quita de los stock_ids de esa campaña.
+	AsignarCampania(ctx context.Context, stockIDs []string, campania models.PromocionCampania, fecha time.Time) error
+	// QuitarCampania quita la campaña de los autos que la tienen, agrega un punto a la
+	// serie de precios de los que cambian de precio y devuelve cuántos autos cambiaron
+	QuitarCampania(ctx context.Context, campaniaID string, fecha time.Time) (int64, error)
+	// CambiarEstado aplica la transición si el auto sigue en la revisión indicada. Guarda
+	// la información del nuevo estado y borra la de los demás.
+	CambiarEstado(ctx context.Context, stockID string, revision int64, transicion models.TransicionEstado, info models.InfoEstado) error
+
+	// RegistrarHistorial guarda una entrada del historial de cambios
+	RegistrarHistorial(ctx context.Context, registro models.RegistroHistorial) error
+	// Historial devuelve los cambios de un auto del más reciente al más antiguo. Con
+	// campo solo devuelve las entradas que modificaron ese campo.
+	Historial(ctx context.Context, stockID string, campo string) ([]models.RegistroHistorial, error)
+
+	// Campanias devuelve las campañas de la más reciente a la más antigua
+	Campanias(ctx context.Context) ([]models.Campania, error)
+	// CrearCampania guarda una campaña nueva. No la asigna a los autos: eso lo hace AsignarCampania.
+	CrearCampania(ctx context.Context, campania models.Campania) error
+	// EliminarCampania elimina la campaña con el id indicado, o devuelve ErrCampaniaNoEncontrada.
+	// No la quita de los autos: eso lo hace QuitarCampania.
+	EliminarCampania(ctx context.Context, campaniaID string) error
+
+	// TablaTiposCambio devuelve los tipos de cambio cargados ordenados por moneda
+	TablaTiposCambio(ctx context.Context) ([]models.TipoCambio, error)
+	// GuardarTipoCambio carga o reemplaza la tasa vigente de la moneda y la agrega a su historial
+	GuardarTipoCambio(ctx context.Context, tipo models.TipoCambio) error
+	// HistorialTipoCambio devuelve las tasas cargadas para la moneda, de la más reciente a
+	// la más antigua
+	HistorialTipoCambio(ctx context.Context, moneda string) ([]models.TipoCambio, error)
+}
+
+// Consulta describe una búsqueda en el catálogo
+type Consulta struct {
+	// Filtro es un filtro de MongoDB. Puede usar $text y los campos calculados precio,
+	// precio_lista, descuento y precio_normalizado.
+	Filtro bson.M
+	// Desde es la condición del cursor de paginación; se aplica después del filtro
+	Desde bson.M
+	// Orden es el orden de los resultados
+	Orden bson.D
+	// Relevancia antepone al orden el puntaje de la búsqueda de texto
+	Relevancia bool
+	Saltar     int64
+	Limite     int64
+}
+
+// Rango es una faceta que cuenta los autos por rangos [limites[i], limites[i+1])
+type Rango struct {
+	Nombre  string
+	Campo   string
+	Limites []float64
+}
+
+// ConsultaFacetas describe las facetas a contar. Cada faceta ignora la condición del
+// filtro sobre su propio campo para mostrar las alternativas disponibles.
+type ConsultaFacetas struct {
+	Filtro      bson.M
+	Categoricas []string
+	Rangos      []Rango
+}
+
+// ConteoFaceta es la cantidad de autos para un valor o para el rango que empieza en Valor.
+// Los autos fuera de todos los rangos se cuentan con el valor "otros".
+type ConteoFaceta struct {
+	Valor    interface{} `bson:"_id"`
+	Cantidad int         `bson:"cantidad"`
+}
+
+// ResultadoFacetas son los conteos de cada faceta, por nombre
+type ResultadoFacetas struct {
+	Total  int
+	Conteo map[string][]ConteoFaceta
+}
+
+// Estadisticas es el resumen del inventario que se expone como métricas
+type Estadisticas struct {
+	// PorEstado y PorSucursal cuentan los autos; los autos sin estado cuentan como disponibles
+	PorEstado   map[string]int64
+	PorSucursal map[string]int64
+	Destacados  int64
+	// ReservasActivas cuenta las reservas con fecha y hora posterior a now
+	ReservasActivas int64
+	// DiasEnStockPromedio es el promedio de días desde el alta de los autos no vendidos
+	DiasEnStockPromedio float64
+}
+
+// FacetaOtros es el valor con el que se cuentan los autos fuera de los rangos
+const FacetaOtros = "otros"
+
+// camposCalculados son los campos que se calculan al leer; sus filtros se aplican
+// después de calcularlos
+var camposCalculados = map[string]bool{
+	"precio":       true,
+	"precio_lista": true,
+	"descuento":    true,
+	// los indicadores de baja de precio dependen de las ventanas de descuento vigentes
+	"precio_anterior":   true,
+	"bajo_de_precio":    true,
+	"bajo_de_precio_en": true,
+	// precio_normalizado es el precio efectivo en la moneda de referencia
+	"precio_normalizado": true,
+}
+
+// sinCampo devuelve una copia del filtro sin la condición sobre el campo indicado
+func sinCampo(filter bson.M, campo string) bson.M {
+	copia := bson.M{}
+	for key, value := range filter {
+		if key != campo {
+			copia[key] = value
+		}
+	}
+	return copia
+}
@@ -0,0 +1,425 @@
+// Package jsonpatch aplica parches JSON Merge Patch (RFC 7396) y JSON Patch (RFC 6902)
+// sobre documentos JSON. Los números se conservan tal como llegan para no perder
+// precisión ni cambiar su formato al volver a codificar el documento.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Tipos de contenido de cada formato de parche
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+var (
+	// ErrParcheInvalido indica que el parche no respeta el formato de su RFC
+	ErrParcheInvalido = errors.New("parche inválido")
+	// ErrNoAplicable indica que el parche es válido pero no se puede aplicar al
+	// documento, por ejemplo porque una ruta no existe o falló una operación test
+	ErrNoAplicable = errors.New("el parche no se puede aplicar")
+)
+
+// Operacion es una operación de un JSON Patch
+type Operacion struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Merge aplica un JSON Merge Patch al documento: los miembros del parche reemplazan a
+// los del documento, los objetos se combinan recursivamente y null elimina el miembro
+func Merge(doc []byte, parche []byte) ([]byte, error) {
+	original, err := decodificar(doc)
+	if err != nil {
+		return nil, err
+	}
+	cambios, err := decodificar(parche)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParcheInvalido, err)
+	}
+	return json.Marshal(merge(original, cambios))
+}
+
+func merge(destino interface{}, parche interface{}) interface{} {
+	cambios, ok := parche.(map[string]interface{})
+	if !ok {
+		return parche
+	}
+	objeto, ok := destino.(map[string]interface{})
+	if !ok {
+		objeto = map[string]interface{}{}
+	}
+	for clave, valor := range cambios {
+		if valor == nil {
+			delete(objeto, clave)
+		} else {
+			objeto[clave] = merge(objeto[clave], valor)
+		}
+	}
+	return objeto
+}
+
+// CamposMerge devuelve los miembros de primer nivel que modifica un Merge Patch. El
+// parche tiene que ser un objeto; cualquier otro valor reemplazaría el documento entero.
+func CamposMerge(parche []byte) ([]string, error) {
+	var cambios map[string]json.RawMessage
+	if err := json.Unmarshal(parche, &cambios); err != nil || cambios == nil {
+		return nil, fmt.Errorf("%w: el parche debe ser un objeto JSON", ErrParcheInvalido)
+	}
+	campos := make([]string, 0, len(cambios))
+	for campo := range cambios {
+		campos = append(campos, campo)
+	}
+	return campos, nil
+}
+
+// Decodificar lee un JSON Patch y verifica que cada operación tenga los miembros que
+// requiere
+func Decodificar(datos []byte) ([]Operacion, error) {
+	var ops []Operacion
+	if err := json.Unmarshal(datos, &ops); err != nil {
+		return nil, fmt.Errorf("%w: el parche debe ser un arreglo de operaciones", ErrParcheInvalido)
+	}
+	for i, op := range ops {
+		if _, err := punteros(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operación %d: %v", ErrParcheInvalido, i, err)
+		}
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operación %d: %s requiere value", ErrParcheInvalido, i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := punteros(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operación %d: from: %v", ErrParcheInvalido, i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operación %d: op desconocida %q", ErrParcheInvalido, i, op.Op)
+		}
+	}
+	return ops, nil
+}
+
+// CamposModificados devuelve los miembros de primer nivel que cambian las operaciones.
+// Las operaciones test no modifican nada; move también modifica el origen. La ruta
+// raíz se informa como "".
+func CamposModificados(ops []Operacion) []string {
+	campos := []string{}
+	for _, op := range ops {
+		if op.Op == "test" {
+			continue
+		}
+		campos = append(campos, campoRaiz(op.Path))
+		if op.Op == "move" {
+			campos = append(campos, campoRaiz(op.From))
+		}
+	}
+	return campos
+}
+
+func campoRaiz(ruta string) string {
+	tokens, err := punteros(ruta)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
+
+// Aplicar aplica las operaciones en orden. Si alguna falla devuelve el error y ningún
+// cambio, porque el documento se modifica sobre una copia decodificada.
+func Aplicar(doc []byte, ops []Operacion) ([]byte, error) {
+	raiz, err := decodificar(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		if raiz, err = aplicarOperacion(raiz, op); err != nil {
+			return nil, fmt.Errorf("operación %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(raiz)
+}
+
+func aplicarOperacion(raiz interface{}, op Operacion) (interface{}, error) {
+	ruta, err := punteros(op.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrParcheInvalido, err)
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		valor, err := decodificar(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: value: %v", ErrParcheInvalido, err)
+		}
+		switch op.Op {
+		case "add":
+			return agregar(raiz, ruta, valor)
+		case "replace":
+			return reemplazar(raiz, ruta, valor)
+		}
+		actual, err := obtener(raiz, ruta)
+		if err != nil {
+			return nil, err
+		}
+		if !iguales(actual, valor) {
+			return nil, fmt.Errorf("%w: el valor no coincide", ErrNoAplicable)
+		}
+		return raiz, nil
+	case "remove":
+		return quitar(raiz, ruta)
+	case "move", "copy":
+		desde, err := punteros(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: from: %v", ErrParcheInvalido, err)
+		}
+		valor, err := obtener(raiz, desde)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return agregar(raiz, ruta, copiar(valor))
+		}
+		if op.Path != op.From && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: no se puede mover un valor dentro de sí mismo", ErrNoAplicable)
+		}
+		if raiz, err = quitar(raiz, desde); err != nil {
+			return nil, err
+		}
+		return agregar(raiz, ruta, valor)
+	}
+	return nil, fmt.Errorf("%w: op desconocida %q", ErrParcheInvalido, op.Op)
+}
+
+// punteros separa un JSON Pointer (RFC 6901) en sus tokens
+func punteros(ruta string) ([]string, error) {
+	if ruta == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(ruta, "/") {
+		return nil, fmt.Errorf("la ruta %q debe empezar con /", ruta)
+	}
+	tokens := strings.Split(ruta[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func obtener(nodo interface{}, ruta []string) (interface{}, error) {
+	for _, token := range ruta {
+		switch n := nodo.(type) {
+		case map[string]interface{}:
+			valor, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: no existe %q", ErrNoAplicable, token)
+			}
+			nodo = valor
+		case []interface{}:
+			i, err := indice(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			nodo = n[i]
+		default:
+			return nil, fmt.Errorf("%w: %q no es un objeto ni un arreglo", ErrNoAplicable, token)
+		}
+	}
+	return nodo, nil
+}
+
+// modificar recorre la ruta hasta el contenedor del último token y le aplica hoja, que
+// devuelve el contenedor modificado. Los arreglos pueden cambiar de tamaño, por eso
+// cada nivel guarda lo que devuelve el nivel siguiente.
+func modificar(nodo interface{}, ruta []string, hoja func(contenedor interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(ruta) == 1 {
+		return hoja(nodo, ruta[0])
+	}
+	token := ruta[0]
+	switch n := nodo.(type) {
+	case map[string]interface{}:
+		hijo, ok := n[token]
+		if !ok {
+			return nil, fmt.Errorf("%w: no existe %q", ErrNoAplicable, token)
+		}
+		nuevo, err := modificar(hijo, ruta[1:], hoja)
+		if err != nil {
+			return nil, err
+		}
+		n[token] = nuevo
+		return n, nil
+	case []interface{}:
+		i, err := indice(token, len(n)-1)
+		if err != nil {
+			return nil, err
+		}
+		nuevo, err := modificar(n[i], ruta[1:], hoja)
+		if err != nil {
+			return nil, err
+		}
+		n[i] = nuevo
+		return n, nil
+	}
+	return nil, fmt.Errorf("%w: %q no es un objeto ni un arreglo", ErrNoAplicable, token)
+}
+
+func agregar(raiz interface{}, ruta []string, valor interface{}) (interface{}, error) {
+	if len(ruta) == 0 {
+		return valor, nil
+	}
+	return modificar(raiz, ruta, func(contenedor interface{}, token string) (interface{}, error) {
+		switch n := contenedor.(type) {
+		case map[string]interface{}:
+			n[token] = valor
+			return n, nil
+		case []interface{}:
+			i := len(n)
+			if token != "-" {
+				var err error
+				if i, err = indice(token, len(n)); err != nil {
+					return nil, err
+				}
+			}
+			n = append(n, nil)
+			copy(n[i+1:], n[i:])
+			n[i] = valor
+			return n, nil
+		}
+		return nil, fmt.Errorf("%w: %q no es un objeto ni un arreglo", ErrNoAplicable, token)
+	})
+}
+
+func quitar(raiz interface{}, ruta []string) (interface{}, error) {
+	if len(ruta) == 0 {
+		return nil, fmt.Errorf("%w: no se puede quitar el documento entero", ErrNoAplicable)
+	}
+	return modificar(raiz, ruta, func(contenedor interface{}, token string) (interface{}, error) {
+		switch n := contenedor.(type) {
+		case map[string]interface{}:
+			if _, ok := n[token]; !ok {
+				return nil, fmt.Errorf("%w: no existe %q", ErrNoAplicable, token)
+			}
+			delete(n, token)
+			return n, nil
+		case []interface{}:
+			i, err := indice(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(n[:i], n[i+1:]...), nil
+		}
+		return nil, fmt.Errorf("%w: %q no es un objeto ni un arreglo", ErrNoAplicable, token)
+	})
+}
+
+func reemplazar(raiz interface{}, ruta []string, valor interface{}) (interface{}, error) {
+	if _, err := obtener(raiz, ruta); err != nil {
+		return nil, err
+	}
+	if len(ruta) == 0 {
+		return valor, nil
+	}
+	return modificar(raiz, ruta, func(contenedor interface{}, token string) (interface{}, error) {
+		switch n := contenedor.(type) {
+		case map[string]interface{}:
+			n[token] = valor
+			return n, nil
+		case []interface{}:
+			i, _ := indice(token, len(n)-1)
+			n[i] = valor
+			return n, nil
+		}
+		return nil, fmt.Errorf("%w: %q no es un objeto ni un arreglo", ErrNoAplicable, token)
+	})
+}
+
+// indice interpreta un token como índice de arreglo entre 0 y max
+func indice(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: %q no es un índice de arreglo", ErrNoAplicable, token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: el índice %d está fuera del arreglo", ErrNoAplicable, i)
+	}
+	return i, nil
+}
+
+func decodificar(datos []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(datos))
+	decoder.UseNumber()
+	var valor interface{}
+	if err := decoder.Decode(&valor); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("hay datos después del valor JSON")
+	}
+	return valor, nil
+}
+
+func copiar(valor interface{}) interface{} {
+	switch v := valor.(type) {
+	case map[string]interface{}:
+		copia := make(map[string]interface{}, len(v))
+		for clave, hijo := range v {
+			copia[clave] = copiar(hijo)
+		}
+		return copia
+	case []interface{}:
+		copia := make([]interface{}, len(v))
+		for i, hijo := range v {
+			copia[i] = copiar(hijo)
+		}
+		return copia
+	}
+	return valor
+}
+
+// iguales compara dos valores JSON; los números se comparan por su valor, así 1 y 1.0
+// son iguales
+func iguales(a interface{}, b interface{}) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for clave, valor := range x {
+			otro, ok := y[clave]
+			if !ok || !iguales(valor, otro) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !iguales(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
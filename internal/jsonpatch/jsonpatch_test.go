@@ -0,0 +1,147 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// igualJSON compara dos documentos JSON sin importar el orden de los miembros
+func igualJSON(t *testing.T, obtenido []byte, esperado string) {
+	t.Helper()
+	var a, b interface{}
+	if err := json.Unmarshal(obtenido, &a); err != nil {
+		t.Fatalf("resultado inválido %s: %v", obtenido, err)
+	}
+	if err := json.Unmarshal([]byte(esperado), &b); err != nil {
+		t.Fatalf("esperado inválido %s: %v", esperado, err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("resultado = %s, se esperaba %s", obtenido, esperado)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// Ejemplos del apéndice A de RFC 7396
+	casos := []struct{ doc, parche, esperado string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"kilometraje":1000000}`, `{"precio":9000}`, `{"kilometraje":1000000,"precio":9000}`},
+	}
+	for _, caso := range casos {
+		resultado, err := Merge([]byte(caso.doc), []byte(caso.parche))
+		if err != nil {
+			t.Errorf("Merge(%s, %s): %v", caso.doc, caso.parche, err)
+			continue
+		}
+		igualJSON(t, resultado, caso.esperado)
+	}
+
+	if _, err := Merge([]byte(`{}`), []byte(`{"a":`)); !errors.Is(err, ErrParcheInvalido) {
+		t.Errorf("parche mal formado: err = %v", err)
+	}
+}
+
+func TestCamposMerge(t *testing.T) {
+	campos, err := CamposMerge([]byte(`{"precio": 1, "reservas": null}`))
+	sort.Strings(campos)
+	if err != nil || !reflect.DeepEqual(campos, []string{"precio", "reservas"}) {
+		t.Errorf("campos = %v, err = %v", campos, err)
+	}
+	for _, parche := range []string{`[]`, `"texto"`, `null`} {
+		if _, err := CamposMerge([]byte(parche)); !errors.Is(err, ErrParcheInvalido) {
+			t.Errorf("CamposMerge(%s): err = %v", parche, err)
+		}
+	}
+}
+
+func TestAplicar(t *testing.T) {
+	// Ejemplos del apéndice A de RFC 6902
+	casos := []struct{ doc, parche, esperado string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":1,"m~n":2}`, `[{"op":"copy","from":"/~1","path":"/m~0n"}]`, `{"/":1,"m~n":1}`},
+	}
+	for _, caso := range casos {
+		ops, err := Decodificar([]byte(caso.parche))
+		if err != nil {
+			t.Errorf("Decodificar(%s): %v", caso.parche, err)
+			continue
+		}
+		resultado, err := Aplicar([]byte(caso.doc), ops)
+		if err != nil {
+			t.Errorf("Aplicar(%s, %s): %v", caso.doc, caso.parche, err)
+			continue
+		}
+		igualJSON(t, resultado, caso.esperado)
+	}
+}
+
+func TestAplicarErrores(t *testing.T) {
+	noAplicables := []struct{ doc, parche string }{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`},
+		{`{"foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`},
+		{`{"foo":"bar"}`, `[{"op":"replace","path":"/baz","value":1}]`},
+		{`{"foo":[1,2]}`, `[{"op":"add","path":"/foo/5","value":3}]`},
+		{`{"foo":[1,2]}`, `[{"op":"remove","path":"/foo/01"}]`},
+		{`{"foo":{"bar":1}}`, `[{"op":"move","from":"/foo","path":"/foo/bar/x"}]`},
+	}
+	for _, caso := range noAplicables {
+		ops, err := Decodificar([]byte(caso.parche))
+		if err != nil {
+			t.Fatalf("Decodificar(%s): %v", caso.parche, err)
+		}
+		if _, err := Aplicar([]byte(caso.doc), ops); !errors.Is(err, ErrNoAplicable) {
+			t.Errorf("Aplicar(%s, %s): err = %v, se esperaba ErrNoAplicable", caso.doc, caso.parche, err)
+		}
+	}
+
+	for _, parche := range []string{
+		`{"op":"add"}`,
+		`[{"op":"borrar","path":"/a"}]`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"remove","path":"a"}]`,
+		`[{"op":"move","path":"/a","from":"b"}]`,
+	} {
+		if _, err := Decodificar([]byte(parche)); !errors.Is(err, ErrParcheInvalido) {
+			t.Errorf("Decodificar(%s): err = %v, se esperaba ErrParcheInvalido", parche, err)
+		}
+	}
+}
+
+func TestCamposModificados(t *testing.T) {
+	ops, err := Decodificar([]byte(`[
+		{"op":"test","path":"/estado","value":"disponible"},
+		{"op":"replace","path":"/precio","value":1},
+		{"op":"add","path":"/imagenes/-","value":"https://img.example.com/1.jpg"},
+		{"op":"move","from":"/reservas/0","path":"/comentarios/0"}
+	]`))
+	if err != nil {
+		t.Fatalf("Decodificar: %v", err)
+	}
+	campos := CamposModificados(ops)
+	if !reflect.DeepEqual(campos, []string{"precio", "imagenes", "comentarios", "reservas"}) {
+		t.Errorf("campos = %v", campos)
+	}
+}
@@ -0,0 +1,57 @@
+// Package logger configura el logger estructurado de la aplicación y lo transporta en
+// el contexto de cada solicitud junto con su request ID.
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+type claveContexto int
+
+const (
+	claveLogger claveContexto = iota
+	claveRequestID
+)
+
+// New crea un logger JSON con el nivel de LOG_LEVEL (debug, info, warn o error; info por defecto)
+func New(w io.Writer) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: nivel(os.Getenv("LOG_LEVEL"))}))
+}
+
+func nivel(valor string) slog.Level {
+	switch strings.ToLower(valor) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewContext devuelve un contexto con el logger y el request ID de la solicitud
+func NewContext(ctx context.Context, l *slog.Logger, requestID string) context.Context {
+	ctx = context.WithValue(ctx, claveLogger, l)
+	return context.WithValue(ctx, claveRequestID, requestID)
+}
+
+// FromContext devuelve el logger de la solicitud, o el logger por defecto si el
+// contexto no tiene uno (por ejemplo fuera de una solicitud HTTP)
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(claveLogger).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// RequestID devuelve el request ID de la solicitud, o "" si el contexto no tiene uno
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(claveRequestID).(string)
+	return id
+}
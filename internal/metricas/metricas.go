@@ -0,0 +1,118 @@
+// Package metricas expone las métricas de la API en formato de texto de Prometheus:
+// solicitudes HTTP por ruta y el resumen del inventario de autos.
+package metricas
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-gorilla-autos/internal/database/repository"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// TimeoutEstadisticas es el tiempo máximo para calcular las métricas del negocio en cada scrape
+const TimeoutEstadisticas = 5 * time.Second
+
+// Metricas agrupa el registro de Prometheus y las métricas HTTP
+type Metricas struct {
+	registro    *prometheus.Registry
+	solicitudes *prometheus.CounterVec
+	duracion    *prometheus.HistogramVec
+}
+
+// New crea las métricas HTTP y registra las del negocio, que se calculan con autos en
+// cada scrape, junto con las del proceso y del runtime de Go
+func New(autos repository.AutoRepository) *Metricas {
+	m := &Metricas{
+		registro: prometheus.NewRegistry(),
+		solicitudes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Cantidad de solicitudes HTTP por método, ruta y código de respuesta.",
+		}, []string{"method", "route", "status"}),
+		duracion: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duración de las solicitudes HTTP por método y ruta.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+
+	m.registro.MustRegister(
+		m.solicitudes,
+		m.duracion,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		&colectorNegocio{autos: autos},
+	)
+	return m
+}
+
+// Handler responde las métricas en formato de texto de Prometheus
+func (m *Metricas) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registro, promhttp.HandlerOpts{})
+}
+
+// ObservarSolicitud registra una solicitud respondida. route es la plantilla de la ruta
+// de mux (por ejemplo /api/autos/{stock_id}) para no crear una serie por cada auto.
+func (m *Metricas) ObservarSolicitud(method string, route string, status int, duracion time.Duration) {
+	m.solicitudes.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	m.duracion.WithLabelValues(method, route).Observe(duracion.Seconds())
+}
+
+var (
+	descPorEstado = prometheus.NewDesc("autos_por_estado",
+		"Cantidad de autos en cada estado.", []string{"estado"}, nil)
+	descPorSucursal = prometheus.NewDesc("autos_por_sucursal",
+		"Cantidad de autos en cada sucursal.", []string{"sucursal"}, nil)
+	descDestacados = prometheus.NewDesc("autos_destacados",
+		"Cantidad de autos destacados.", nil, nil)
+	descReservasActivas = prometheus.NewDesc("autos_reservas_activas",
+		"Cantidad de reservas con fecha futura.", nil, nil)
+	descDiasEnStock = prometheus.NewDesc("autos_dias_en_stock_promedio",
+		"Promedio de días desde el alta de los autos no vendidos.", nil, nil)
+	descEstadisticasError = prometheus.NewDesc("autos_estadisticas_error",
+		"1 si no se pudieron calcular las métricas del negocio en este scrape.", nil, nil)
+)
+
+// colectorNegocio calcula las métricas del inventario al momento de cada scrape, así
+// siempre reflejan la base aunque los cambios los haga otra instancia
+type colectorNegocio struct {
+	autos repository.AutoRepository
+}
+
+func (c *colectorNegocio) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descPorEstado
+	ch <- descPorSucursal
+	ch <- descDestacados
+	ch <- descReservasActivas
+	ch <- descDiasEnStock
+	ch <- descEstadisticasError
+}
+
+func (c *colectorNegocio) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), TimeoutEstadisticas)
+	defer cancel()
+
+	estadisticas, err := c.autos.Estadisticas(ctx, time.Now())
+	if err != nil {
+		log.Printf("Error calculating business metrics: %v", err)
+		ch <- prometheus.MustNewConstMetric(descEstadisticasError, prometheus.GaugeValue, 1)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(descEstadisticasError, prometheus.GaugeValue, 0)
+
+	for estado, cantidad := range estadisticas.PorEstado {
+		ch <- prometheus.MustNewConstMetric(descPorEstado, prometheus.GaugeValue, float64(cantidad), estado)
+	}
+	for sucursal, cantidad := range estadisticas.PorSucursal {
+		ch <- prometheus.MustNewConstMetric(descPorSucursal, prometheus.GaugeValue, float64(cantidad), sucursal)
+	}
+	ch <- prometheus.MustNewConstMetric(descDestacados, prometheus.GaugeValue, float64(estadisticas.Destacados))
+	ch <- prometheus.MustNewConstMetric(descReservasActivas, prometheus.GaugeValue, float64(estadisticas.ReservasActivas))
+	ch <- prometheus.MustNewConstMetric(descDiasEnStock, prometheus.GaugeValue, estadisticas.DiasEnStockPromedio)
+}
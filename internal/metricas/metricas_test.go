@@ -0,0 +1,82 @@
+package metricas_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/metricas"
+	"go-gorilla-autos/internal/server/routes/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func scrape(t *testing.T, m *metricas.Metricas) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	return string(body)
+}
+
+func TestMetricasPorRuta(t *testing.T) {
+	m := metricas.New(repository.NewMemoria())
+	r := mux.NewRouter()
+	r.Use(middleware.MetricasMiddleware(m))
+	r.HandleFunc("/api/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["stock_id"] == "T0099" {
+			http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		}
+	})
+
+	for _, url := range []string{"/api/autos/T0001", "/api/autos/T0002", "/api/autos/T0099"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", url, nil))
+	}
+
+	texto := scrape(t, m)
+	for _, esperado := range []string{
+		`http_requests_total{method="GET",route="/api/autos/{stock_id}",status="200"} 2`,
+		`http_requests_total{method="GET",route="/api/autos/{stock_id}",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/api/autos/{stock_id}"} 3`,
+	} {
+		if !strings.Contains(texto, esperado) {
+			t.Errorf("falta %q en las métricas", esperado)
+		}
+	}
+	if strings.Contains(texto, "T0001") {
+		t.Error("las métricas no deben usar el stock_id como etiqueta")
+	}
+}
+
+func TestMetricasNegocio(t *testing.T) {
+	repo := repository.NewMemoria()
+	for _, auto := range []models.Auto{
+		{StockID: "T0001", Marca: "Toyota", Sucursal: "Centro", Estado: models.EstadoDisponible, Featured: true, CreatedAt: time.Now()},
+		{StockID: "T0002", Marca: "Toyota", Sucursal: "Norte", Estado: models.EstadoVendido, CreatedAt: time.Now(),
+			Reservas: []models.Reserva{{ID: "A001", FechaHora: time.Now().Add(time.Hour)}}},
+	} {
+		if err := repo.Create(context.Background(), auto); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	texto := scrape(t, metricas.New(repo))
+	for _, esperado := range []string{
+		`autos_por_estado{estado="disponible"} 1`,
+		`autos_por_estado{estado="vendido"} 1`,
+		`autos_por_sucursal{sucursal="Norte"} 1`,
+		`autos_destacados 1`,
+		`autos_reservas_activas 1`,
+		`autos_estadisticas_error 0`,
+	} {
+		if !strings.Contains(texto, esperado) {
+			t.Errorf("falta %q en las métricas", esperado)
+		}
+	}
+}
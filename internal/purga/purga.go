@@ -0,0 +1,92 @@
+package purga
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"go-gorilla-autos/internal/database/repository"
+)
+
+// Valores por defecto de la purga de la papelera
+const (
+	DiasDefault      = 30
+	IntervaloDefault = time.Hour
+	// TimeoutPurga es el tiempo máximo de cada ejecución de la purga
+	TimeoutPurga = time.Minute
+)
+
+// Config es la configuración de la purga de la papelera
+type Config struct {
+	// Dias es la cantidad de días que un auto queda en la papelera antes de eliminarse
+	// definitivamente. Con 0 la purga está desactivada.
+	Dias int
+	// Intervalo es cada cuánto se buscan autos para purgar
+	Intervalo time.Duration
+}
+
+// ConfigDesdeEntorno arma la configuración con PAPELERA_DIAS y PAPELERA_INTERVALO,
+// usando los valores por defecto para las que no están definidas
+func ConfigDesdeEntorno() (Config, error) {
+	cfg := Config{Dias: DiasDefault, Intervalo: IntervaloDefault}
+
+	if valor := os.Getenv("PAPELERA_DIAS"); valor != "" {
+		dias, err := strconv.Atoi(valor)
+		if err != nil || dias < 0 {
+			return Config{}, fmt.Errorf("PAPELERA_DIAS inválido: %q", valor)
+		}
+		cfg.Dias = dias
+	}
+	if valor := os.Getenv("PAPELERA_INTERVALO"); valor != "" {
+		intervalo, err := time.ParseDuration(valor)
+		if err != nil || intervalo <= 0 {
+			return Config{}, fmt.Errorf("PAPELERA_INTERVALO inválido: %q", valor)
+		}
+		cfg.Intervalo = intervalo
+	}
+	return cfg, nil
+}
+
+// Purgar elimina definitivamente los autos que están en la papelera desde hace más de
+// los días configurados, contados desde now
+func Purgar(ctx context.Context, autos repository.AutoRepository, cfg Config, now time.Time) (int64, error) {
+	return autos.Purgar(ctx, now.AddDate(0, 0, -cfg.Dias))
+}
+
+// Iniciar purga la papelera al arrancar y después en cada intervalo, hasta que se
+// cancele ctx. Los errores se registran en el log y se reintenta en el intervalo siguiente.
+func Iniciar(ctx context.Context, autos repository.AutoRepository, cfg Config) {
+	if cfg.Dias == 0 {
+		slog.Info("Trash purge disabled")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Intervalo)
+	defer ticker.Stop()
+	for {
+		ejecutar(ctx, autos, cfg)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ejecutar hace una purga con su propio timeout y registra el resultado
+func ejecutar(ctx context.Context, autos repository.AutoRepository, cfg Config) {
+	ctx, cancel := context.WithTimeout(ctx, TimeoutPurga)
+	defer cancel()
+
+	purgados, err := Purgar(ctx, autos, cfg, time.Now())
+	if err != nil {
+		slog.Error("Error purging trash", "error", err)
+		return
+	}
+	if purgados > 0 {
+		slog.Info("Trash purged", "autos", purgados, "dias", cfg.Dias)
+	}
+}
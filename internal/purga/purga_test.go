@@ -0,0 +1,58 @@
+package purga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+)
+
+func TestConfigDesdeEntorno(t *testing.T) {
+	t.Setenv("PAPELERA_DIAS", "")
+	t.Setenv("PAPELERA_INTERVALO", "")
+	cfg, err := ConfigDesdeEntorno()
+	if err != nil || cfg.Dias != DiasDefault || cfg.Intervalo != IntervaloDefault {
+		t.Errorf("config por defecto = %+v, err = %v", cfg, err)
+	}
+
+	t.Setenv("PAPELERA_DIAS", "0")
+	t.Setenv("PAPELERA_INTERVALO", "15m")
+	cfg, err = ConfigDesdeEntorno()
+	if err != nil || cfg.Dias != 0 || cfg.Intervalo != 15*time.Minute {
+		t.Errorf("config = %+v, err = %v", cfg, err)
+	}
+
+	for clave, valor := range map[string]string{"PAPELERA_DIAS": "-1", "PAPELERA_INTERVALO": "0s"} {
+		t.Run(clave, func(t *testing.T) {
+			t.Setenv(clave, valor)
+			if _, err := ConfigDesdeEntorno(); err == nil {
+				t.Errorf("%s=%s: se esperaba un error", clave, valor)
+			}
+		})
+	}
+}
+
+func TestPurgar(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoria()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for stockID, dias := range map[string]int{"T0001": 31, "T0002": 29} {
+		if err := repo.Create(ctx, models.Auto{StockID: stockID}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if err := repo.Delete(ctx, stockID, 1, now.AddDate(0, 0, -dias)); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+
+	purgados, err := Purgar(ctx, repo, Config{Dias: 30}, now)
+	if err != nil || purgados != 1 {
+		t.Fatalf("purgados = %d, err = %v, se esperaba 1", purgados, err)
+	}
+	papelera, _ := repo.Papelera(ctx)
+	if len(papelera) != 1 || papelera[0].StockID != "T0002" {
+		t.Errorf("papelera = %+v, se esperaba solo T0002", papelera)
+	}
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag devuelve la etiqueta de entidad de una revisión de un auto
+func ETag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// SetETag informa en el encabezado ETag la revisión del auto de la respuesta
+func SetETag(w http.ResponseWriter, revision int64) {
+	w.Header().Set("ETag", ETag(revision))
+}
+
+// CumpleIfMatch indica si la revisión cumple la precondición If-Match de la solicitud.
+// Sin el encabezado se cumple siempre. La comparación es fuerte, así que una etiqueta
+// débil (W/"...") nunca coincide.
+func CumpleIfMatch(r *http.Request, revision int64) bool {
+	valores := r.Header.Values("If-Match")
+	if len(valores) == 0 {
+		return true
+	}
+	etag := ETag(revision)
+	for _, valor := range valores {
+		for _, candidata := range strings.Split(valor, ",") {
+			candidata = strings.TrimSpace(candidata)
+			if candidata == "*" || candidata == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PrecondicionFallidaResponse responde 412 cuando If-Match no coincide con la revisión
+// actual del auto, que se informa en ETag para que el cliente pueda volver a leerlo
+func PrecondicionFallidaResponse(w http.ResponseWriter, r *http.Request, revision int64) {
+	SetETag(w, revision)
+	ErrorResponse(w, r, CodigoPrecondicionFallida, "El auto fue modificado desde la revisión indicada en If-Match")
+}
+
+// RevisionCambiadaResponse responde a una escritura que otra solicitud ganó entre la
+// lectura y el guardado: 412 si el cliente envió If-Match, o el código indicado si no
+func RevisionCambiadaResponse(w http.ResponseWriter, r *http.Request, codigo CodigoError) {
+	if r.Header.Get("If-Match") != "" {
+		ErrorResponse(w, r, CodigoPrecondicionFallida, "El auto fue modificado desde la revisión indicada en If-Match")
+		return
+	}
+	ErrorResponse(w, r, codigo, "El auto cambió mientras se procesaba la solicitud")
+}
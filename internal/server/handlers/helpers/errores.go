@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"net/http"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/logger"
+)
+
+// CodigoError identifica el tipo de error para los clientes de la API. El frontend
+// decide qué mostrar según el código, así que los valores existentes no se renombran.
+type CodigoError string
+
+// Catálogo de códigos de error
+const (
+	CodigoJSONInvalido          CodigoError = "json_invalido"
+	CodigoParametroInvalido     CodigoError = "parametro_invalido"
+	CodigoValidacion            CodigoError = "validacion"
+	CodigoCamposInvalidos       CodigoError = "campos_invalidos"
+	CodigoParcheInvalido        CodigoError = "parche_invalido"
+	CodigoParcheNoAplicable     CodigoError = "parche_no_aplicable"
+	CodigoTipoContenido         CodigoError = "tipo_contenido_no_soportado"
+	CodigoDescuentoInvalido     CodigoError = "descuento_invalido"
+	CodigoDescuentoExistente    CodigoError = "descuento_existente"
+	CodigoSinDescuento          CodigoError = "sin_descuento"
+	CodigoNoAutorizado          CodigoError = "no_autorizado"
+	CodigoUsuarioRequerido      CodigoError = "usuario_requerido"
+	CodigoAutoNoEncontrado      CodigoError = "auto_no_encontrado"
+	CodigoReservaNoEncontrada   CodigoError = "reserva_no_encontrada"
+	CodigoCampaniaNoEncontrada  CodigoError = "campania_no_encontrada"
+	CodigoSinDestacados         CodigoError = "sin_destacados"
+	CodigoRutaNoEncontrada      CodigoError = "ruta_no_encontrada"
+	CodigoMetodoNoPermitido     CodigoError = "metodo_no_permitido"
+	CodigoTransicionNoPermitida CodigoError = "transicion_no_permitida"
+	CodigoEstadoCambiado        CodigoError = "estado_cambiado"
+	CodigoAutoModificado        CodigoError = "auto_modificado"
+	CodigoReservaDuplicada      CodigoError = "reserva_duplicada"
+	CodigoPrecondicionFallida   CodigoError = "precondicion_fallida"
+	CodigoInterno               CodigoError = "error_interno"
+)
+
+// statusPorCodigo es el código HTTP que corresponde a cada código de error
+var statusPorCodigo = map[CodigoError]int{
+	CodigoJSONInvalido:          http.StatusBadRequest,
+	CodigoParametroInvalido:     http.StatusBadRequest,
+	CodigoValidacion:            http.StatusBadRequest,
+	CodigoCamposInvalidos:       http.StatusUnprocessableEntity,
+	CodigoParcheInvalido:        http.StatusBadRequest,
+	CodigoParcheNoAplicable:     http.StatusConflict,
+	CodigoTipoContenido:         http.StatusUnsupportedMediaType,
+	CodigoDescuentoInvalido:     http.StatusBadRequest,
+	CodigoDescuentoExistente:    http.StatusBadRequest,
+	CodigoSinDescuento:          http.StatusBadRequest,
+	CodigoNoAutorizado:          http.StatusUnauthorized,
+	CodigoUsuarioRequerido:      http.StatusBadRequest,
+	CodigoAutoNoEncontrado:      http.StatusNotFound,
+	CodigoReservaNoEncontrada:   http.StatusNotFound,
+	CodigoCampaniaNoEncontrada:  http.StatusNotFound,
+	CodigoSinDestacados:         http.StatusNotFound,
+	CodigoRutaNoEncontrada:      http.StatusNotFound,
+	CodigoMetodoNoPermitido:     http.StatusMethodNotAllowed,
+	CodigoTransicionNoPermitida: http.StatusConflict,
+	CodigoEstadoCambiado:        http.StatusConflict,
+	CodigoAutoModificado:        http.StatusConflict,
+	CodigoReservaDuplicada:      http.StatusConflict,
+	CodigoPrecondicionFallida:   http.StatusPreconditionFailed,
+	CodigoInterno:               http.StatusInternalServerError,
+}
+
+// Status devuelve el código HTTP del error; 500 si el código no está en el catálogo
+func (c CodigoError) Status() int {
+	if status, ok := statusPorCodigo[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorAPI es el cuerpo de todas las respuestas de error, dentro de la clave "error"
+type ErrorAPI struct {
+	Code      CodigoError `json:"code"`
+	Mensaje   string      `json:"mensaje"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// ErrorResponse escribe un error con el formato común y el código HTTP que corresponde al código
+func ErrorResponse(w http.ResponseWriter, r *http.Request, codigo CodigoError, mensaje string) {
+	ErrorResponseConDetalles(w, r, codigo, mensaje, nil)
+}
+
+// ErrorResponseConDetalles escribe un error con información adicional para el cliente,
+// por ejemplo los campos que fallaron la validación
+func ErrorResponseConDetalles(w http.ResponseWriter, r *http.Request, codigo CodigoError, mensaje string, details interface{}) {
+	JSONResponse(w, codigo.Status(), map[string]ErrorAPI{
+		"error": {
+			Code:      codigo,
+			Mensaje:   mensaje,
+			Details:   details,
+			RequestID: logger.RequestID(r.Context()),
+		},
+	})
+}
+
+// CamposInvalidosResponse responde 422 con un error por campo, para que el formulario
+// pueda marcar cada uno
+func CamposInvalidosResponse(w http.ResponseWriter, r *http.Request, errores models.ErroresValidacion) {
+	ErrorResponseConDetalles(w, r, CodigoCamposInvalidos, "Hay campos inválidos", map[string]interface{}{
+		"campos": errores,
+	})
+}
+
+// NotFoundHandler responde con el formato común a las rutas que no existen
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	ErrorResponse(w, r, CodigoRutaNoEncontrada, "Ruta no encontrada")
+}
+
+// MethodNotAllowedHandler responde con el formato común a los métodos no soportados por una ruta
+func MethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	ErrorResponse(w, r, CodigoMetodoNoPermitido, "Método no permitido")
+}
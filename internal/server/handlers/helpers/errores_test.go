@@ -0,0 +1,50 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-gorilla-autos/internal/logger"
+)
+
+func TestErrorResponseConDetalles(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(logger.NewContext(context.Background(), slog.Default(), "abc-123"))
+	rec := httptest.NewRecorder()
+
+	ErrorResponseConDetalles(rec, req, CodigoValidacion, "faltan campos", map[string][]string{"campos": {"marca"}})
+
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status = %d, content-type = %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var respuesta struct {
+		Error struct {
+			Code      string              `json:"code"`
+			Mensaje   string              `json:"mensaje"`
+			Details   map[string][]string `json:"details"`
+			RequestID string              `json:"request_id"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&respuesta); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+	e := respuesta.Error
+	if e.Code != "validacion" || e.Mensaje != "faltan campos" || e.RequestID != "abc-123" || len(e.Details["campos"]) != 1 {
+		t.Errorf("error = %+v", e)
+	}
+}
+
+func TestCodigosConStatus(t *testing.T) {
+	for codigo, status := range statusPorCodigo {
+		if status < 400 || status > 599 {
+			t.Errorf("%s: status %d no es un error", codigo, status)
+		}
+	}
+	if got := CodigoError("desconocido").Status(); got != http.StatusInternalServerError {
+		t.Errorf("código desconocido: status = %d, se esperaba 500", got)
+	}
+}
@@ -14,11 +14,6 @@ func JSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	}
 }
 
-// JSONErrorResponse escribe una respuesta de error JSON
-func JSONErrorResponse(w http.ResponseWriter, statusCode int, message string) {
-	JSONResponse(w, statusCode, map[string]string{"error": message})
-}
-
 // JSONSuccessResponse escribe una respuesta exitosa JSON con mensaje
 func JSONSuccessResponse(w http.ResponseWriter, statusCode int, message string, data map[string]interface{}) {
 	response := map[string]interface{}{"mensaje": message}
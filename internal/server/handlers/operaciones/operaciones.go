@@ -0,0 +1,60 @@
+// Package operaciones tiene los endpoints para las sondas de Docker/Kubernetes y el
+// monitoreo. No requieren autenticación ni dependen de la lógica del catálogo.
+package operaciones
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/version"
+)
+
+// TimeoutReadyz es el tiempo máximo que espera /readyz la respuesta de MongoDB
+const TimeoutReadyz = 2 * time.Second
+
+// HealthzHandler responde 200 mientras el proceso esté vivo. No consulta dependencias
+// para que un problema de la base no haga reiniciar el contenedor.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	helpers.JSONResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler responde 200 si MongoDB responde un ping, con la latencia del ping,
+// y 503 si no responde dentro de TimeoutReadyz
+func ReadyzHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	ctx, cancel := context.WithTimeout(r.Context(), TimeoutReadyz)
+	defer cancel()
+
+	inicio := time.Now()
+	err := db.Health(ctx)
+	latencia := time.Since(inicio)
+
+	mongo := map[string]interface{}{
+		"status":      "ok",
+		"latencia_ms": float64(latencia.Microseconds()) / 1000,
+	}
+	if err != nil {
+		mongo["status"] = "error"
+		mongo["error"] = err.Error()
+		helpers.JSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "unavailable",
+			"mongo":  mongo,
+		})
+		return
+	}
+
+	helpers.JSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"mongo":  mongo,
+	})
+}
+
+// VersionHandler responde el commit, la fecha de compilación y la versión de Go del binario
+func VersionHandler(w http.ResponseWriter, r *http.Request) {
+	helpers.JSONResponse(w, http.StatusOK, version.Get())
+}
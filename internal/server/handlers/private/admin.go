@@ -1,89 +1,140 @@
 package private
 
 import (
-	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func CreateAutoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// maxIntentosStockID es la cantidad de stock_id que se prueban al crear un auto
+const maxIntentosStockID = 3
+
+func CreateAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var auto models.Auto
 	if err := json.NewDecoder(r.Body).Decode(&auto); err != nil {
-		http.Error(w, "Error al decodificar el JSON", http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
+		return
+	}
+
+	// Validar campos requeridos. La moneda debe tener tipo de cambio cargado.
+	tipos, err := autos.TiposCambio(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching exchange rates", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los tipos de cambio")
+		return
+	}
+	if errores, err := auto.ValidateRequired(tipos); err != nil {
+		helpers.CamposInvalidosResponse(w, r, errores)
 		return
 	}
 
-	// Validar campos requeridos
-	if _, err := auto.ValidateRequired(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Los autos se crean disponibles; los cambios de estado pasan por /status
+	if auto.Estado != models.EstadoDisponible {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Un auto nuevo debe crearse como disponible")
 		return
 	}
 
+	// Los demás campos protegidos los administra el servidor y un auto nuevo los tiene
+	// vacíos. precio_anterior, bajo_de_precio* e historial_precios los inicia
+	// IniciarHistorialPrecios.
+	auto.Revision = 0
+	auto.DeletedAt = nil
+	auto.Reservas = nil
+	auto.EstadoActualizadoEn = time.Time{}
+	auto.HistorialEstados = nil
+	auto.ReservadoPor = nil
+	auto.VendidoPor = nil
+	auto.EnNegociacion = nil
+	auto.EnMantenimiento = nil
+
+	auto.ActualizarCaracteristicasTexto()
+
 	// Establecer created_at y updated_at
 	now := time.Now()
 	auto.CreatedAt = now
 	auto.UpdatedAt = now
 
-	collection := db.Collection("autos")
-
-	// Generar stock_id
-	firstLetter := strings.ToUpper(string(auto.Marca[0]))
-
-	// Buscar el último número usado para esta marca
-	var lastAuto models.Auto
-	opts := options.FindOne().SetSort(bson.M{"stock_id": -1})
-	err := collection.FindOne(context.Background(),
-		bson.M{"stock_id": bson.M{"$regex": "^" + firstLetter}},
-		opts).Decode(&lastAuto)
+	// precio es el precio de lista; un descuento inicial se guarda como promoción por monto.
+	// Las campañas se asignan solo al crearlas desde /campaigns.
+	auto.Campania = nil
+	if auto.PrecioLista <= 0 {
+		auto.PrecioLista = auto.Precio
+	}
+	if auto.Promocion == nil && auto.Descuento > 0 {
+		auto.Promocion = &models.Promocion{Tipo: models.DescuentoMonto, Valor: auto.Descuento}
+	}
+	if auto.Promocion != nil {
+		auto.Promocion.CreadoEn = now
+		if err := auto.Promocion.Validar(auto.PrecioLista); err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, err.Error())
+			return
+		}
+		if err := auto.Promocion.ValidarMoneda(auto.Moneda); err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, err.Error())
+			return
+		}
+		if auto.Promocion.Tipo == models.DescuentoMonto {
+			auto.Promocion.Moneda = auto.Moneda
+		}
+	}
+	auto.AplicarPrecioEfectivo(now)
+	auto.IniciarHistorialPrecios(now)
 
-	var num int
-	if err == mongo.ErrNoDocuments {
-		num = 1
-	} else if err != nil {
-		http.Error(w, "Error al generar stock_id", http.StatusInternalServerError)
+	// Generar stock_id con la secuencia atómica de la letra de la marca. El índice único
+	// rechaza un stock_id repetido (por ejemplo cargado a mano), en ese caso se pide otro.
+	prefijo, err := models.PrefijoStockID(auto.Marca)
+	if err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, err.Error())
 		return
-	} else {
-		// Extraer el número del último stock_id
-		numStr := lastAuto.StockID[1:]
-		num, _ = strconv.Atoi(numStr)
-		num++
 	}
 
-	// Generar nuevo stock_id
-	auto.StockID = fmt.Sprintf("%s%02d", firstLetter, num)
-
-	// Crear el auto con el stock_id generado
-	_, err = collection.InsertOne(context.Background(), auto)
+	for intento := 0; intento < maxIntentosStockID; intento++ {
+		var num int64
+		num, err = autos.NextStockIDNumber(r.Context(), prefijo)
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error generating stock_id", "error", err)
+			helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al generar stock_id")
+			return
+		}
+		auto.StockID = models.FormatStockID(prefijo, num)
+
+		// Crear el auto con el stock_id generado
+		err = autos.Create(r.Context(), auto)
+		if !errors.Is(err, repository.ErrStockIDDuplicado) {
+			break
+		}
+	}
 	if err != nil {
-		http.Error(w, "Error al guardar el auto", http.StatusInternalServerError)
+		logger.FromContext(r.Context()).Error("Error saving auto", "stock_id", auto.StockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al guardar el auto")
 		return
 	}
 
+	// Los autos nuevos se guardan en la revisión 1
+	auto.Revision = 1
 	response := map[string]interface{}{
 		"mensaje": "Auto creado exitosamente",
 		"auto":    auto,
 	}
 
+	helpers.SetETag(w, auto.Revision)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
 }
 
 // UpdateAutoHandler actualiza un auto existente usando stock_id
-func UpdateAutoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func UpdateAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -92,61 +143,149 @@ func UpdateAutoHandler(w http.ResponseWriter, r *http.Request, db database.Servi
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
 	// Obtener auto existente
-	collection := db.Collection("autos")
-	var existingAuto models.Auto
-	err := collection.FindOne(context.Background(), bson.M{"stock_id": stockID}).Decode(&existingAuto)
+	existingAuto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	if !helpers.CumpleIfMatch(r, existingAuto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, existingAuto.Revision)
 		return
 	}
 
 	// Decodificar datos actualizados
 	var updateData models.Auto
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
-		http.Error(w, "Error al decodificar el JSON", http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
 
-	// Validar campos requeridos
-	if _, err := updateData.ValidateRequired(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	guardarActualizacion(w, r, autos, existingAuto, updateData)
+}
+
+// guardarActualizacion valida el auto actualizado por PUT o PATCH, conserva los campos
+// que administra el servidor, lo guarda y registra los cambios en el historial. Solo
+// guarda si nadie modificó el auto desde que se leyó existingAuto.
+func guardarActualizacion(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository, existingAuto models.Auto, updateData models.Auto) {
+	stockID := existingAuto.StockID
+
+	// Validar campos requeridos. La moneda debe tener tipo de cambio cargado.
+	tipos, err := autos.TiposCambio(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching exchange rates", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los tipos de cambio")
+		return
+	}
+	if errores, err := updateData.ValidateRequired(tipos); err != nil {
+		helpers.CamposInvalidosResponse(w, r, errores)
 		return
 	}
 
-	// Mantener el stock_id original
+	// Mantener el stock_id, la fecha de creación y las reservas, que tienen sus propios endpoints
 	updateData.StockID = stockID
+	updateData.Revision = existingAuto.Revision
+	updateData.DeletedAt = existingAuto.DeletedAt
+	updateData.CreatedAt = existingAuto.CreatedAt
+	updateData.Reservas = existingAuto.Reservas
+
+	// El estado solo cambia a través de /status, que valida las transiciones
+	updateData.Estado = existingAuto.Estado
+	updateData.EstadoActualizadoEn = existingAuto.EstadoActualizadoEn
+	updateData.HistorialEstados = existingAuto.HistorialEstados
+	updateData.ReservadoPor = existingAuto.ReservadoPor
+	updateData.VendidoPor = existingAuto.VendidoPor
+	updateData.EnNegociacion = existingAuto.EnNegociacion
+	updateData.EnMantenimiento = existingAuto.EnMantenimiento
+
+	// El precio de lista viene en precio_lista; si no viene se toma precio, salvo que sea
+	// el precio efectivo actual devuelto sin cambios. La promoción solo cambia por /discount.
+	now := time.Now()
+	existingAuto.AplicarPrecioEfectivo(now)
+	if updateData.PrecioLista <= 0 {
+		updateData.PrecioLista = updateData.Precio
+		if updateData.Precio == existingAuto.Precio {
+			updateData.PrecioLista = existingAuto.PrecioLista
+		}
+	}
+	updateData.Promocion = existingAuto.Promocion
+	updateData.Campania = existingAuto.Campania
+	if updateData.Promocion != nil {
+		if err := updateData.Promocion.Validar(updateData.PrecioLista); err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, "El nuevo precio de lista no es compatible con el descuento vigente: "+err.Error())
+			return
+		}
+		if err := updateData.Promocion.ValidarMoneda(updateData.Moneda); err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, "La nueva moneda no es compatible con el descuento vigente: "+err.Error())
+			return
+		}
+	}
+	if updateData.Campania != nil {
+		if err := updateData.Campania.Promocion.ValidarMoneda(updateData.Moneda); err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, "La nueva moneda no es compatible con la campaña vigente: "+err.Error())
+			return
+		}
+	}
+	updateData.AplicarPrecioEfectivo(now)
 
-	// Establecer updated_at
-	updateData.UpdatedAt = time.Now()
+	// La serie de precios se mantiene y se agrega un punto si cambió el precio
+	precios := existingAuto
+	if updateData.Precio != existingAuto.Precio {
+		precios.CambioPrecio(updateData.Precio, models.MotivoPrecioActualizacion, now)
+	}
+	updateData.HistorialPrecios = precios.HistorialPrecios
+	updateData.PrecioAnterior = precios.PrecioAnterior
+	updateData.BajoDePrecio = precios.BajoDePrecio
+	updateData.BajoDePrecioEn = precios.BajoDePrecioEn
 
-	// Actualizar el auto
-	update := bson.M{"$set": updateData}
-	result, err := collection.UpdateOne(context.Background(), bson.M{"stock_id": stockID}, update)
-	if err != nil {
-		http.Error(w, "Error al actualizar el auto", http.StatusInternalServerError)
+	// Establecer updated_at
+	updateData.UpdatedAt = now
+	updateData.ActualizarCaracteristicasTexto()
+
+	// Actualizar el auto si sigue en la revisión leída
+	if err := autos.Update(r.Context(), updateData); err != nil {
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		if errors.Is(err, repository.ErrRevisionCambiada) {
+			helpers.RevisionCambiadaResponse(w, r, helpers.CodigoAutoModificado)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error updating auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al actualizar el auto")
 		return
 	}
 
-	if result.MatchedCount == 0 {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
-		return
+	// Registrar los campos modificados en el historial
+	if cambios, err := models.DiffAutos(existingAuto, updateData); err != nil {
+		logger.FromContext(r.Context()).Error("Error calculando cambios", "stock_id", stockID, "error", err)
+	} else {
+		historial.Registrar(r.Context(), autos, r, stockID, cambios)
 	}
 
+	updateData.Revision++
 	response := map[string]interface{}{
 		"mensaje": "Auto actualizado exitosamente",
 		"auto":    updateData,
 	}
 
+	helpers.SetETag(w, updateData.Revision)
 	json.NewEncoder(w).Encode(response)
 }
 
-// DeleteAutoHandler elimina un auto usando stock_id
-func DeleteAutoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// DeleteAutoHandler mueve un auto a la papelera usando stock_id. Se puede restaurar
+// hasta que la purga lo elimine definitivamente.
+func DeleteAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -155,27 +294,97 @@ func DeleteAutoHandler(w http.ResponseWriter, r *http.Request, db database.Servi
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
-	collection := db.Collection("autos")
-	filter := bson.M{"stock_id": stockID}
-
-	result, err := collection.DeleteOne(context.Background(), filter)
+	// Se lee la revisión para no borrar un auto que otra persona modificó mientras tanto
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error al eliminar el auto", http.StatusInternalServerError)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	if !helpers.CumpleIfMatch(r, auto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, auto.Revision)
 		return
 	}
 
-	if result.DeletedCount == 0 {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+	now := time.Now()
+	if err := autos.Delete(r.Context(), stockID, auto.Revision, now); err != nil {
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		if errors.Is(err, repository.ErrRevisionCambiada) {
+			helpers.RevisionCambiadaResponse(w, r, helpers.CodigoAutoModificado)
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error deleting auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al eliminar el auto")
 		return
 	}
 
-	response := map[string]string{
-		"mensaje": "Auto eliminado exitosamente",
+	historial.Registrar(r.Context(), autos, r, stockID, []models.CambioCampo{{Campo: "deleted_at", Anterior: nil, Nuevo: now}})
+
+	response := map[string]interface{}{
+		"mensaje":    "Auto enviado a la papelera",
+		"deleted_at": now,
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// GetAutosAdminHandler obtiene todos los autos con la información completa para el panel de administración
+func GetAutosAdminHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	lista, err := autos.FindAll(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching autos", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los autos")
+		return
+	}
+
+	// Calcular el precio vigente de cada auto
+	now := time.Now()
+	for i := range lista {
+		lista[i].AplicarPrecioEfectivo(now)
+	}
+
+	json.NewEncoder(w).Encode(lista)
+}
+
+// GetAutoAdminHandler obtiene un auto con la información completa usando stock_id
+func GetAutoAdminHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
+
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	auto.AplicarPrecioEfectivo(time.Now())
+
+	helpers.SetETag(w, auto.Revision)
+	json.NewEncoder(w).Encode(auto)
+}
@@ -0,0 +1,264 @@
+package campanias
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
+	"go-gorilla-autos/internal/server/handlers/public"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AutoAlcanzado describe cómo afecta una campaña a un auto que cumple su filtro
+type AutoAlcanzado struct {
+	StockID           string  `json:"stock_id"`
+	Marca             string  `json:"marca"`
+	Modelo            string  `json:"modelo"`
+	Moneda            string  `json:"moneda"`
+	PrecioLista       float64 `json:"precio_lista"`
+	PrecioActual      float64 `json:"precio_actual"`
+	PrecioConCampania float64 `json:"precio_con_campania"`
+	Aplicable         bool    `json:"aplicable"`
+	Motivo            string  `json:"motivo,omitempty"`
+	CampaniaAnterior  string  `json:"campania_anterior,omitempty"`
+}
+
+// CampaniaResponse es una campaña con su estado de vigencia calculado
+type CampaniaResponse struct {
+	models.Campania `bson:",inline"`
+	Activa          bool `json:"activa"`
+}
+
+// PreviewCampaniaHandler muestra qué autos alcanzaría una campaña y su precio resultante,
+// sin aplicar cambios
+func PreviewCampaniaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	campania, ok := decodeCampania(w, r)
+	if !ok {
+		return
+	}
+
+	alcanzados, ok := buscarAlcanzados(w, r, autos, campania)
+	if !ok {
+		return
+	}
+
+	response := map[string]interface{}{
+		"campania":   campania,
+		"autos":      alcanzados,
+		"total":      len(alcanzados),
+		"aplicables": contarAplicables(alcanzados),
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// CrearCampaniaHandler crea una campaña y la aplica a los autos que cumplen su filtro.
+// Los autos alcanzados quedan fijados al crearla, igual que en la vista previa; si un
+// auto ya tenía otra campaña, la nueva la reemplaza.
+func CrearCampaniaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	campania, ok := decodeCampania(w, r)
+	if !ok {
+		return
+	}
+
+	alcanzados, ok := buscarAlcanzados(w, r, autos, campania)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	campania.ID = primitive.NewObjectID().Hex()
+	campania.CreadoEn = now
+	campania.Promocion.CreadoEn = now
+	campania.StockIDs = []string{}
+	for _, alcanzado := range alcanzados {
+		if alcanzado.Aplicable {
+			campania.StockIDs = append(campania.StockIDs, alcanzado.StockID)
+		}
+	}
+
+	if err := autos.CrearCampania(r.Context(), campania); err != nil {
+		logger.FromContext(r.Context()).Error("Error saving campaign", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al guardar la campaña")
+		return
+	}
+
+	if len(campania.StockIDs) > 0 {
+		if err := autos.AsignarCampania(r.Context(), campania.StockIDs, campania.PromocionParaAutos(), now); err != nil {
+			logger.FromContext(r.Context()).Error("Error applying campaign", "campania_id", campania.ID, "error", err)
+			helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al aplicar la campaña")
+			return
+		}
+
+		for _, alcanzado := range alcanzados {
+			if !alcanzado.Aplicable {
+				continue
+			}
+			historial.Registrar(r.Context(), autos, r, alcanzado.StockID, []models.CambioCampo{
+				{Campo: "campania", Anterior: alcanzado.CampaniaAnterior, Nuevo: campania.ID},
+			})
+		}
+	}
+
+	response := map[string]interface{}{
+		"mensaje":  "Campaña creada exitosamente",
+		"campania": campania,
+		"total":    len(campania.StockIDs),
+	}
+	helpers.JSONResponse(w, http.StatusCreated, response)
+}
+
+// ListarCampaniasHandler lista las campañas, de la más reciente a la más antigua
+func ListarCampaniasHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	campanias, err := autos.Campanias(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching campaigns", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener las campañas")
+		return
+	}
+
+	now := time.Now()
+	response := make([]CampaniaResponse, 0, len(campanias))
+	for _, campania := range campanias {
+		response = append(response, CampaniaResponse{Campania: campania, Activa: campania.Activa(now)})
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// EliminarCampaniaHandler elimina una campaña y la quita de los autos que la tienen
+func EliminarCampaniaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	campaniaID := vars["campania_id"]
+
+	if err := autos.EliminarCampania(r.Context(), campaniaID); err != nil {
+		if errors.Is(err, repository.ErrCampaniaNoEncontrada) {
+			helpers.ErrorResponse(w, r, helpers.CodigoCampaniaNoEncontrada, "Campaña no encontrada")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error deleting campaign", "campania_id", campaniaID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al eliminar la campaña")
+		return
+	}
+
+	modificados, err := autos.QuitarCampania(r.Context(), campaniaID, time.Now())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error removing campaign from autos", "campania_id", campaniaID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al quitar la campaña de los autos")
+		return
+	}
+
+	response := map[string]interface{}{
+		"mensaje": "Campaña eliminada exitosamente",
+		"autos":   modificados,
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// decodeCampania decodifica y valida la campaña del body. Si falla escribe la respuesta.
+func decodeCampania(w http.ResponseWriter, r *http.Request) (models.Campania, bool) {
+	var campania models.Campania
+	if err := json.NewDecoder(r.Body).Decode(&campania); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
+		return campania, false
+	}
+	if err := campania.Validar(); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, err.Error())
+		return campania, false
+	}
+	return campania, true
+}
+
+// buscarAlcanzados busca los autos no vendidos que cumplen el filtro de la campaña y
+// calcula el precio que tendrían con ella. Si falla escribe la respuesta.
+func buscarAlcanzados(w http.ResponseWriter, r *http.Request, repo repository.AutoRepository, campania models.Campania) ([]AutoAlcanzado, bool) {
+	// El filtro se interpreta igual que en el catálogo, pero siempre en modo estricto
+	qp := public.NewQueryParserFromMap(campania.Filtro)
+	qp.Strict = true
+	filter := public.BuildAutosFilter(qp)
+	if invalidos := qp.Validate(public.ParametrosCatalogo); invalidos != nil {
+		helpers.ErrorResponseConDetalles(w, r, helpers.CodigoValidacion, "filtro inválido: "+invalidos.Error(), invalidos)
+		return nil, false
+	}
+	if cond, ok := filter["estado"].(bson.M); ok {
+		cond["$ne"] = models.EstadoVendido
+	} else {
+		filter["estado"] = bson.M{"$ne": models.EstadoVendido}
+	}
+
+	autos, err := repo.Filter(r.Context(), repository.Consulta{
+		Filtro: filter,
+		Orden:  bson.D{{Key: "stock_id", Value: 1}},
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching campaign autos", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al buscar los autos de la campaña")
+		return nil, false
+	}
+
+	// El precio con campaña se calcula para el inicio de la vigencia
+	desde := time.Now()
+	if campania.Promocion.ValidoDesde != nil && campania.Promocion.ValidoDesde.After(desde) {
+		desde = *campania.Promocion.ValidoDesde
+	}
+	promocion := campania.PromocionParaAutos()
+
+	alcanzados := make([]AutoAlcanzado, 0, len(autos))
+	for _, auto := range autos {
+		alcanzado := AutoAlcanzado{
+			StockID:      auto.StockID,
+			Marca:        auto.Marca,
+			Modelo:       auto.Modelo,
+			Moneda:       auto.Moneda,
+			PrecioLista:  auto.PrecioLista,
+			PrecioActual: auto.Precio,
+			Aplicable:    true,
+		}
+		if auto.Campania != nil {
+			alcanzado.CampaniaAnterior = auto.Campania.CampaniaID
+		}
+
+		err := campania.Promocion.Validar(auto.PrecioLista)
+		if err == nil {
+			err = campania.Promocion.ValidarMoneda(auto.Moneda)
+		}
+		if err != nil {
+			alcanzado.Aplicable = false
+			alcanzado.Motivo = err.Error()
+			alcanzado.PrecioConCampania = auto.Precio
+		} else {
+			conCampania := auto
+			conCampania.Campania = &promocion
+			conCampania.AplicarPrecioEfectivo(desde)
+			alcanzado.PrecioConCampania = conCampania.Precio
+		}
+		alcanzados = append(alcanzados, alcanzado)
+	}
+	return alcanzados, true
+}
+
+// contarAplicables cuenta los autos a los que se les puede aplicar la campaña
+func contarAplicables(alcanzados []AutoAlcanzado) int {
+	total := 0
+	for _, alcanzado := range alcanzados {
+		if alcanzado.Aplicable {
+			total++
+		}
+	}
+	return total
+}
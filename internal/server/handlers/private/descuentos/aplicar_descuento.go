@@ -1,20 +1,26 @@
 package descuentos
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
 	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
-// AplicarDescuentoHandler aplica un descuento a un auto
-func AplicarDescuentoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// AplicarDescuentoHandler aplica un descuento a un auto sin modificar su precio de lista.
+// Acepta descuentos por porcentaje o por monto fijo, opcionalmente con una ventana de
+// vigencia; el precio efectivo se calcula al leer. Un descuento por monto sin moneda se
+// toma en la moneda del auto. Por compatibilidad, {"descuento": X} equivale a un
+// descuento por monto sin vencimiento.
+func AplicarDescuentoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -23,83 +29,125 @@ func AplicarDescuentoHandler(w http.ResponseWriter, r *http.Request, db database
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
 	// Decodificar el descuento
 	var descuentoRequest struct {
-		Descuento float64 `json:"descuento"`
+		Descuento   float64    `json:"descuento"`
+		Tipo        string     `json:"tipo"`
+		Valor       float64    `json:"valor"`
+		Moneda      string     `json:"moneda"`
+		ValidoDesde *time.Time `json:"valido_desde"`
+		ValidoHasta *time.Time `json:"valido_hasta"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&descuentoRequest); err != nil {
-		http.Error(w, "Error al decodificar el JSON", http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
 
-	// Validar descuento
-	if descuentoRequest.Descuento < 0 {
-		http.Error(w, "Descuento no puede ser negativo", http.StatusBadRequest)
-		return
+	now := time.Now()
+	promocion := models.Promocion{
+		Tipo:        descuentoRequest.Tipo,
+		Valor:       descuentoRequest.Valor,
+		Moneda:      descuentoRequest.Moneda,
+		ValidoDesde: descuentoRequest.ValidoDesde,
+		ValidoHasta: descuentoRequest.ValidoHasta,
+		CreadoEn:    now,
+	}
+	if promocion.Tipo == "" && descuentoRequest.Descuento != 0 {
+		promocion.Tipo = models.DescuentoMonto
+		promocion.Valor = descuentoRequest.Descuento
 	}
-
-	collection := db.Collection("autos")
 
 	// Buscar el auto por stock_id
-	var auto models.Auto
-	filter := bson.M{"stock_id": stockID}
-	err := collection.FindOne(context.Background(), filter).Decode(&auto)
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
 		return
 	}
-
-	// Verificar si ya existe un descuento
-	if auto.Descuento > 0 {
-		http.Error(w, "Ya existe un descuento para este auto. Elimínelo primero para agregar uno nuevo", http.StatusBadRequest)
+	if !helpers.CumpleIfMatch(r, auto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, auto.Revision)
 		return
 	}
 
-	// Validar que el descuento no supere el precio original
-	if descuentoRequest.Descuento > auto.Precio {
-		http.Error(w, "El descuento no puede ser mayor al precio original", http.StatusBadRequest)
+	// Verificar si ya existe un descuento
+	if auto.Promocion != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoDescuentoExistente, "Ya existe un descuento para este auto. Elimínelo primero para agregar uno nuevo")
 		return
 	}
 
-	// Calcular precio con descuento
-	precioOriginal := auto.Precio
-	precioConDescuento := precioOriginal - descuentoRequest.Descuento
-
-	// Validar que el precio con descuento sea positivo
-	if precioConDescuento <= 0 {
-		http.Error(w, "El descuento no puede reducir el precio a cero o negativo", http.StatusBadRequest)
+	// Validar el descuento contra el precio de lista
+	precioLista := auto.PrecioDeLista()
+	if err := promocion.Validar(precioLista); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, err.Error())
 		return
 	}
-
-	// Actualizar el descuento y el precio
-	update := bson.M{
-		"$set": bson.M{
-			"descuento": descuentoRequest.Descuento,
-			"precio":    precioConDescuento,
-		},
+	if err := promocion.ValidarMoneda(auto.Moneda); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoDescuentoInvalido, err.Error())
+		return
 	}
-	_, err = collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		http.Error(w, "Error al aplicar el descuento", http.StatusInternalServerError)
+	if promocion.Tipo == models.DescuentoMonto {
+		promocion.Moneda = models.NormalizarMoneda(auto.Moneda)
+	}
+
+	// El precio de lista no cambia; precio y descuento se guardan con el valor vigente
+	// ahora y se recalculan en cada lectura según la ventana de la promoción
+	auto.AplicarPrecioEfectivo(now)
+	precioAnterior := auto.Precio
+	conPromocion := auto
+	conPromocion.Promocion = &promocion
+	conPromocion.AplicarPrecioEfectivo(now)
+
+	if conPromocion.Precio != precioAnterior {
+		auto.CambioPrecio(conPromocion.Precio, models.MotivoPrecioDescuento, now)
+	}
+	auto.PrecioLista = precioLista
+	auto.Promocion = &promocion
+	auto.Descuento = conPromocion.Descuento
+	auto.UpdatedAt = now
+
+	// Solo se guarda si el auto no cambió desde que se leyó
+	if err := autos.UpdatePrecios(r.Context(), auto); err != nil {
+		if errors.Is(err, repository.ErrRevisionCambiada) {
+			helpers.RevisionCambiadaResponse(w, r, helpers.CodigoAutoModificado)
+			return
+		}
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error applying discount", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al aplicar el descuento")
 		return
 	}
 
+	historial.Registrar(r.Context(), autos, r, stockID, []models.CambioCampo{
+		{Campo: "promocion", Anterior: nil, Nuevo: promocion},
+	})
+
 	response := map[string]interface{}{
 		"mensaje":              "Descuento aplicado exitosamente",
-		"descuento":            descuentoRequest.Descuento,
-		"precio_original":      precioOriginal,
-		"precio_con_descuento": precioConDescuento,
+		"promocion":            promocion,
+		"activo":               promocion.Activa(now),
+		"descuento":            conPromocion.Descuento,
+		"precio_lista":         precioLista,
+		"precio_con_descuento": conPromocion.Precio,
 	}
 
+	helpers.SetETag(w, auto.Revision+1)
 	helpers.JSONResponse(w, http.StatusOK, response)
 }
 
-// EliminarDescuentoHandler elimina el descuento de un auto
-func EliminarDescuentoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// EliminarDescuentoHandler elimina el descuento de un auto. El precio vuelve a ser el
+// precio de lista, que nunca se modificó, menos el descuento de campaña si hay uno vigente.
+func EliminarDescuentoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -108,48 +156,75 @@ func EliminarDescuentoHandler(w http.ResponseWriter, r *http.Request, db databas
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
-	collection := db.Collection("autos")
-
 	// Buscar el auto por stock_id
-	var auto models.Auto
-	filter := bson.M{"stock_id": stockID}
-	err := collection.FindOne(context.Background(), filter).Decode(&auto)
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	if !helpers.CumpleIfMatch(r, auto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, auto.Revision)
 		return
 	}
 
 	// Verificar si hay descuento para eliminar
-	if auto.Descuento == 0 {
-		http.Error(w, "No hay descuento aplicado para este auto", http.StatusBadRequest)
+	if auto.Promocion == nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoSinDescuento, "No hay descuento aplicado para este auto")
 		return
 	}
 
-	// Calcular precio original (sumando el descuento)
-	precioOriginal := auto.Precio + auto.Descuento
+	// Sin la promoción puede seguir vigente el descuento de una campaña
+	now := time.Now()
+	auto.AplicarPrecioEfectivo(now)
+	precioAnterior := auto.Precio
+	promocion := auto.Promocion
+	sinPromocion := auto
+	sinPromocion.Promocion = nil
+	sinPromocion.AplicarPrecioEfectivo(now)
 
 	// Actualizar eliminando el descuento
-	update := bson.M{
-		"$set": bson.M{
-			"descuento": 0,
-			"precio":    precioOriginal,
-		},
-	}
-	_, err = collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		http.Error(w, "Error al eliminar el descuento", http.StatusInternalServerError)
+	if sinPromocion.Precio != precioAnterior {
+		auto.CambioPrecio(sinPromocion.Precio, models.MotivoPrecioSinDescuento, now)
+	}
+	auto.Promocion = nil
+	auto.Descuento = sinPromocion.Descuento
+	auto.UpdatedAt = now
+
+	// Solo se guarda si el auto no cambió desde que se leyó
+	if err := autos.UpdatePrecios(r.Context(), auto); err != nil {
+		if errors.Is(err, repository.ErrRevisionCambiada) {
+			helpers.RevisionCambiadaResponse(w, r, helpers.CodigoAutoModificado)
+			return
+		}
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error removing discount", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al eliminar el descuento")
 		return
 	}
 
+	historial.Registrar(r.Context(), autos, r, stockID, []models.CambioCampo{
+		{Campo: "promocion", Anterior: promocion, Nuevo: nil},
+	})
+
 	response := map[string]interface{}{
-		"mensaje":         "Descuento eliminado exitosamente",
-		"precio_original": precioOriginal,
-		"descuento":       0,
+		"mensaje":      "Descuento eliminado exitosamente",
+		"precio_lista": sinPromocion.PrecioLista,
+		"descuento":    sinPromocion.Descuento,
+		"precio":       sinPromocion.Precio,
 	}
 
+	helpers.SetETag(w, auto.Revision+1)
 	helpers.JSONResponse(w, http.StatusOK, response)
 }
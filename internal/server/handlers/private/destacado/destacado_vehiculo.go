@@ -1,19 +1,20 @@
 package destacado
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 // ToggleFeaturedHandler marca o desmarca un auto como destacado
-func ToggleFeaturedHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func ToggleFeaturedHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -22,26 +23,26 @@ func ToggleFeaturedHandler(w http.ResponseWriter, r *http.Request, db database.S
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
-	collection := db.Collection("autos")
-
 	// Buscar el auto por stock_id
-	var auto models.Auto
-	filter := bson.M{"stock_id": stockID}
-	err := collection.FindOne(context.Background(), filter).Decode(&auto)
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
 		return
 	}
 
 	// Cambiar el estado featured
-	update := bson.M{"$set": bson.M{"featured": !auto.Featured}}
-	_, err = collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		http.Error(w, "Error al actualizar el auto", http.StatusInternalServerError)
+	if err := autos.SetFeatured(r.Context(), stockID, !auto.Featured); err != nil {
+		logger.FromContext(r.Context()).Error("Error updating auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al actualizar el auto")
 		return
 	}
 
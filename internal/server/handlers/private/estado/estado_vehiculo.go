@@ -1,24 +1,24 @@
 package estado
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"slices"
+	"time"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
 	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
 
 	"github.com/gorilla/mux"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
-// Estados válidos: "Disponible", "En negociación", "Reservado", "Vendido", "En mantenimiento"
-// TODO: Validar que el estado recibido coincida con los estados definidos arriba
-
-// CambiarEstadoAutoHandler cambia el estado de un auto
-func CambiarEstadoAutoHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// CambiarEstadoAutoHandler cambia el estado de un auto respetando la máquina de estados
+// definida en models. Las transiciones no permitidas responden 409; un administrador
+// puede forzarlas con "forzar": true.
+func CambiarEstadoAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -27,65 +27,100 @@ func CambiarEstadoAutoHandler(w http.ResponseWriter, r *http.Request, db databas
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
 	// Decodificar el nuevo estado
 	var estadoRequest struct {
 		Estado          string                    `json:"estado"`
+		Forzar          bool                      `json:"forzar"`
 		ReservadoPor    *models.ReservadoInfo     `json:"reservado_por,omitempty"`
 		VendidoPor      *models.VendidoInfo       `json:"vendido_por,omitempty"`
 		EnNegociacion   *models.NegociacionInfo   `json:"en_negociacion,omitempty"`
 		EnMantenimiento *models.MantenimientoInfo `json:"en_mantenimiento,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&estadoRequest); err != nil {
-		http.Error(w, "Error al decodificar el JSON", http.StatusBadRequest)
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
 
 	// Validar estado
-	validStates := []string{"disponible", "reservado", "vendido", "en negociación", "en mantenimiento"}
-	if !slices.Contains(validStates, estadoRequest.Estado) {
-		http.Error(w, "Estado inválido", http.StatusBadRequest)
+	if !models.EsEstadoValido(estadoRequest.Estado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Estado inválido")
 		return
 	}
 
-	collection := db.Collection("autos")
+	// Validar que venga la información que requiere el nuevo estado
+	info := models.InfoEstado{
+		ReservadoPor:    estadoRequest.ReservadoPor,
+		VendidoPor:      estadoRequest.VendidoPor,
+		EnNegociacion:   estadoRequest.EnNegociacion,
+		EnMantenimiento: estadoRequest.EnMantenimiento,
+	}
+	if err := info.Validar(estadoRequest.Estado); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, err.Error())
+		return
+	}
 
 	// Buscar el auto por stock_id
-	var auto models.Auto
-	filter := bson.M{"stock_id": stockID}
-	err := collection.FindOne(context.Background(), filter).Decode(&auto)
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Auto no encontrado", http.StatusNotFound)
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	if !helpers.CumpleIfMatch(r, auto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, auto.Revision)
 		return
 	}
 
-	// Actualizar el estado y la información correspondiente
-	update := bson.M{"$set": bson.M{"estado": estadoRequest.Estado}}
-
-	switch estadoRequest.Estado {
-	case "reservado":
-		update["$set"].(bson.M)["reservado_por"] = estadoRequest.ReservadoPor
-	case "vendido":
-		update["$set"].(bson.M)["vendido_por"] = estadoRequest.VendidoPor
-	case "en negociación":
-		update["$set"].(bson.M)["en_negociacion"] = estadoRequest.EnNegociacion
-	case "en mantenimiento":
-		update["$set"].(bson.M)["en_mantenimiento"] = estadoRequest.EnMantenimiento
+	// Validar la transición
+	desde := auto.EstadoActual()
+	if err := models.ValidarTransicion(desde, estadoRequest.Estado, estadoRequest.Forzar); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoTransicionNoPermitida, err.Error())
+		return
 	}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		http.Error(w, "Error al actualizar el estado del auto", http.StatusInternalServerError)
+	now := time.Now()
+	transicion := models.TransicionEstado{
+		Desde:   desde,
+		Hacia:   estadoRequest.Estado,
+		Fecha:   now,
+		Forzada: estadoRequest.Forzar,
+	}
+
+	// Solo se actualiza si el auto no cambió desde que se leyó
+	if err := autos.CambiarEstado(r.Context(), stockID, auto.Revision, transicion, info); err != nil {
+		if errors.Is(err, repository.ErrRevisionCambiada) {
+			helpers.RevisionCambiadaResponse(w, r, helpers.CodigoEstadoCambiado)
+			return
+		}
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error updating auto estado", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al actualizar el estado del auto")
 		return
 	}
 
+	cambios := []models.CambioCampo{{Campo: "estado", Anterior: desde, Nuevo: estadoRequest.Estado}}
+	if campo := models.CampoInfoEstado(estadoRequest.Estado); campo != "" {
+		cambios = append(cambios, models.CambioCampo{Campo: campo, Anterior: nil, Nuevo: info.Valor(estadoRequest.Estado)})
+	}
+	historial.Registrar(r.Context(), autos, r, stockID, cambios)
+
 	response := map[string]interface{}{
-		"mensaje": "Estado del auto actualizado exitosamente",
-		"estado":  estadoRequest.Estado,
+		"mensaje":    "Estado del auto actualizado exitosamente",
+		"estado":     estadoRequest.Estado,
+		"transicion": transicion,
 	}
 
+	helpers.SetETag(w, auto.Revision+1)
 	helpers.JSONResponse(w, http.StatusOK, response)
 }
@@ -0,0 +1,84 @@
+package historial
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+
+	"github.com/gorilla/mux"
+)
+
+// HeaderUsuario es el header con el que el panel identifica a quien hace el cambio
+const HeaderUsuario = "X-Usuario"
+
+// Usuario devuelve quién hace la request según el header X-Usuario. El valor lo declara
+// quien llama: la autenticación es un token compartido que no identifica a la persona,
+// así que el historial registra al usuario declarado, no a uno verificado. Las
+// escrituras privadas sin el header se rechazan en middleware.UsuarioMiddleware.
+func Usuario(r *http.Request) string {
+	return strings.TrimSpace(r.Header.Get(HeaderUsuario))
+}
+
+// Registrar guarda en auto_history los cambios hechos sobre un auto por la request.
+// Si no hay cambios no guarda nada. Los errores se registran en el log pero no se
+// devuelven, para no fallar una operación que ya se aplicó.
+func Registrar(ctx context.Context, autos repository.AutoRepository, r *http.Request, stockID string, cambios []models.CambioCampo) {
+	if len(cambios) == 0 {
+		return
+	}
+
+	endpoint := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			endpoint = template
+		}
+	}
+
+	registro := models.RegistroHistorial{
+		StockID:  stockID,
+		Fecha:    time.Now(),
+		Usuario:  Usuario(r),
+		Metodo:   r.Method,
+		Endpoint: endpoint,
+		Cambios:  cambios,
+	}
+	if err := autos.RegistrarHistorial(ctx, registro); err != nil {
+		logger.FromContext(ctx).Error("Error registrando historial", "stock_id", stockID, "error", err)
+	}
+}
+
+// GetHistorialHandler devuelve el historial de cambios de un auto, del más reciente al
+// más antiguo. Admite filtrar por campo con ?campo=precio.
+func GetHistorialHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
+
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	registros, err := autos.Historial(r.Context(), stockID, r.URL.Query().Get("campo"))
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching history", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el historial")
+		return
+	}
+
+	response := map[string]interface{}{
+		"stock_id":  stockID,
+		"historial": registros,
+		"total":     len(registros),
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
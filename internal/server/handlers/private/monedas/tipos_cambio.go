@@ -0,0 +1,114 @@
+package monedas
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
+
+	"github.com/gorilla/mux"
+)
+
+// ListarTiposCambioHandler lista las tasas vigentes y las monedas de autos que todavía
+// no tienen tipo de cambio cargado
+func ListarTiposCambioHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	tipos, err := autos.TablaTiposCambio(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching exchange rates", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los tipos de cambio")
+		return
+	}
+
+	monedas, err := autos.Monedas(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching auto currencies", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener las monedas de los autos")
+		return
+	}
+	cargadas := map[string]bool{models.MonedaReferencia: true}
+	for _, tipo := range tipos {
+		cargadas[tipo.Moneda] = true
+	}
+	sinTipoCambio := []string{}
+	for _, moneda := range monedas {
+		moneda = models.NormalizarMoneda(moneda)
+		if moneda != "" && !cargadas[moneda] {
+			cargadas[moneda] = true
+			sinTipoCambio = append(sinTipoCambio, moneda)
+		}
+	}
+	sort.Strings(sinTipoCambio)
+
+	response := map[string]interface{}{
+		"moneda_referencia": models.MonedaReferencia,
+		"tipos_cambio":      tipos,
+		"sin_tipo_cambio":   sinTipoCambio,
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// ActualizarTipoCambioHandler carga o actualiza la tasa de una moneda y la guarda en el
+// historial de tipos de cambio
+func ActualizarTipoCambioHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request struct {
+		Tasa float64 `json:"tasa"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
+		return
+	}
+
+	tipo := models.TipoCambio{
+		Moneda:        mux.Vars(r)["moneda"],
+		Tasa:          request.Tasa,
+		ActualizadoEn: time.Now(),
+		Usuario:       historial.Usuario(r),
+	}
+	if err := tipo.Validar(); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, err.Error())
+		return
+	}
+
+	if err := autos.GuardarTipoCambio(r.Context(), tipo); err != nil {
+		logger.FromContext(r.Context()).Error("Error saving exchange rate", "moneda", tipo.Moneda, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al guardar el tipo de cambio")
+		return
+	}
+
+	response := map[string]interface{}{
+		"mensaje":     "Tipo de cambio actualizado exitosamente",
+		"tipo_cambio": tipo,
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// GetHistorialTipoCambioHandler devuelve las tasas cargadas para una moneda, de la más
+// reciente a la más antigua
+func GetHistorialTipoCambioHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	moneda := models.NormalizarMoneda(mux.Vars(r)["moneda"])
+
+	tipos, err := autos.HistorialTipoCambio(r.Context(), moneda)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching exchange rate history", "moneda", moneda, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el historial de tipos de cambio")
+		return
+	}
+
+	response := map[string]interface{}{
+		"moneda":    moneda,
+		"historial": tipos,
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
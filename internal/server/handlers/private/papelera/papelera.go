@@ -0,0 +1,72 @@
+package papelera
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
+
+	"github.com/gorilla/mux"
+)
+
+// ListarPapeleraHandler devuelve los autos eliminados que todavía se pueden restaurar,
+// del borrado más reciente al más antiguo
+func ListarPapeleraHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	lista, err := autos.Papelera(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching trash", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener la papelera")
+		return
+	}
+
+	// Calcular el precio vigente de cada auto, como en el listado del panel
+	now := time.Now()
+	for i := range lista {
+		lista[i].AplicarPrecioEfectivo(now)
+	}
+
+	helpers.JSONResponse(w, http.StatusOK, map[string]interface{}{
+		"autos": lista,
+		"total": len(lista),
+	})
+}
+
+// RestaurarAutoHandler saca un auto de la papelera con sus reservas e imágenes
+func RestaurarAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
+
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	auto, err := autos.Restaurar(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "El auto no está en la papelera")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error restoring auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al restaurar el auto")
+		return
+	}
+
+	// Restaurar devuelve el auto como estaba en la papelera
+	historial.Registrar(r.Context(), autos, r, stockID, []models.CambioCampo{{Campo: "deleted_at", Anterior: auto.DeletedAt, Nuevo: nil}})
+	auto.DeletedAt = nil
+	auto.Revision++
+	auto.AplicarPrecioEfectivo(time.Now())
+
+	helpers.SetETag(w, auto.Revision)
+	helpers.JSONSuccessResponse(w, http.StatusOK, "Auto restaurado exitosamente", map[string]interface{}{
+		"auto": auto,
+	})
+}
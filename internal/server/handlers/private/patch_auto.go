@@ -0,0 +1,124 @@
+package private
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/jsonpatch"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+
+	"github.com/gorilla/mux"
+)
+
+// formatosPatch son los tipos de contenido que acepta PATCH, informados en Accept-Patch
+var formatosPatch = strings.Join([]string{jsonpatch.ContentTypeMergePatch, jsonpatch.ContentTypeJSONPatch}, ", ")
+
+// PatchAutoHandler actualiza solo los campos que envía el cliente. Acepta JSON Merge
+// Patch (application/merge-patch+json, o application/json) y JSON Patch
+// (application/json-patch+json). El parche se aplica sobre el auto guardado y el
+// resultado se valida igual que en PUT. Los campos que administra el servidor se
+// rechazan con 422. Como PUT, respeta If-Match y no pisa cambios concurrentes.
+func PatchAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Accept-Patch", formatosPatch)
+
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
+
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	tipo, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (tipo != jsonpatch.ContentTypeMergePatch && tipo != jsonpatch.ContentTypeJSONPatch && tipo != "application/json") {
+		helpers.ErrorResponse(w, r, helpers.CodigoTipoContenido, "Tipo de contenido no soportado, se acepta "+formatosPatch)
+		return
+	}
+
+	parche, err := io.ReadAll(r.Body)
+	if err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParcheInvalido, "Error al leer el parche")
+		return
+	}
+
+	// Verificar el parche y que no toque campos protegidos antes de leer el auto
+	var campos []string
+	var ops []jsonpatch.Operacion
+	if tipo == jsonpatch.ContentTypeJSONPatch {
+		if ops, err = jsonpatch.Decodificar(parche); err == nil {
+			campos = jsonpatch.CamposModificados(ops)
+		}
+	} else {
+		campos, err = jsonpatch.CamposMerge(parche)
+	}
+	if err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParcheInvalido, err.Error())
+		return
+	}
+	if errores := models.ValidarCamposEditables(campos); len(errores) > 0 {
+		helpers.CamposInvalidosResponse(w, r, errores)
+		return
+	}
+
+	// Obtener auto existente
+	existingAuto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	if !helpers.CumpleIfMatch(r, existingAuto.Revision) {
+		helpers.PrecondicionFallidaResponse(w, r, existingAuto.Revision)
+		return
+	}
+
+	// El parche se aplica sobre el auto tal como lo devuelve GET, con el precio vigente
+	existingAuto.AplicarPrecioEfectivo(time.Now())
+	doc, err := json.Marshal(existingAuto)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error encoding auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al aplicar el parche")
+		return
+	}
+	if tipo == jsonpatch.ContentTypeJSONPatch {
+		doc, err = jsonpatch.Aplicar(doc, ops)
+	} else {
+		doc, err = jsonpatch.Merge(doc, parche)
+	}
+	if errors.Is(err, jsonpatch.ErrNoAplicable) {
+		helpers.ErrorResponse(w, r, helpers.CodigoParcheNoAplicable, err.Error())
+		return
+	}
+	if err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParcheInvalido, err.Error())
+		return
+	}
+
+	var updateData models.Auto
+	if err := json.Unmarshal(doc, &updateData); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParcheInvalido, "El parche deja campos con un tipo inválido: "+err.Error())
+		return
+	}
+
+	// Cambiar solo precio equivale a cargar un nuevo precio de lista, como en PUT
+	if updateData.PrecioLista == existingAuto.PrecioLista && updateData.Precio != existingAuto.Precio {
+		updateData.PrecioLista = updateData.Precio
+	}
+
+	guardarActualizacion(w, r, autos, existingAuto, updateData)
+}
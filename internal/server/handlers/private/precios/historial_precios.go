@@ -0,0 +1,56 @@
+package precios
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+
+	"github.com/gorilla/mux"
+)
+
+// GetHistorialPreciosHandler devuelve la serie de precios de un auto para graficarla,
+// incluyendo los cambios por ventanas de descuento que se abrieron o cerraron desde la
+// última escritura
+func GetHistorialPreciosHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
+
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	auto, err := autos.FindByStockID(r.Context(), stockID)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
+	now := time.Now()
+	serie := auto.SeriePrecios(now)
+	auto.AplicarPrecioEfectivo(now)
+
+	response := map[string]interface{}{
+		"stock_id":        stockID,
+		"moneda":          auto.Moneda,
+		"precio_actual":   auto.Precio,
+		"precio_lista":    auto.PrecioLista,
+		"precio_anterior": auto.PrecioAnterior,
+		"bajo_de_precio":  auto.BajoDePrecio,
+		"historial":       serie,
+	}
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
@@ -2,10 +2,13 @@ package reserva
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
 	publicReserva "go-gorilla-autos/internal/server/handlers/public/reserva"
 
 	"github.com/gorilla/mux"
@@ -21,14 +24,14 @@ func writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{})
 }
 
 // CrearReservaHandler maneja la creación de una nueva reserva
-func CrearReservaHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func CrearReservaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	stockID := vars["stock_id"]
 
 	var reserva models.Reserva
 	if err := json.NewDecoder(r.Body).Decode(&reserva); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusBadRequest, "Error al decodificar el JSON")
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
 
@@ -37,29 +40,19 @@ func CrearReservaHandler(w http.ResponseWriter, r *http.Request, db database.Ser
 
 	// Validaciones básicas
 	if reserva.Nombre == "" || reserva.Apellido == "" {
-		publicReserva.WriteErrorResponse(w, http.StatusBadRequest, "Nombre y apellido son requeridos")
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Nombre y apellido son requeridos")
 		return
 	}
 
 	if reserva.FechaHora.IsZero() {
-		publicReserva.WriteErrorResponse(w, http.StatusBadRequest, "Fecha y hora de reserva son requeridas")
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Fecha y hora de reserva son requeridas")
 		return
 	}
 
-	// Usar helper del paquete público para buscar auto
-	result := publicReserva.FindAutoByStockID(r.Context(), db, stockID)
-	if !result.Found {
-		publicReserva.WriteNotFoundResponse(w, "Auto no encontrado")
-		return
-	}
-	auto := result.Auto
-
-	// Agregar la nueva reserva
-	auto.Reservas = append(auto.Reservas, reserva)
-
-	// Usar helper del paquete público para actualizar
-	if err := publicReserva.UpdateAutoReservas(r.Context(), db, stockID, auto.Reservas); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusInternalServerError, "Error al actualizar las reservas")
+	// Agregar la reserva de forma atómica, sin pisar las que se crean al mismo tiempo
+	auto, err := autos.AgregarReserva(r.Context(), stockID, reserva)
+	if err != nil {
+		writeErrorReserva(w, r, stockID, err)
 		return
 	}
 
@@ -71,42 +64,14 @@ func CrearReservaHandler(w http.ResponseWriter, r *http.Request, db database.Ser
 	writeJSONResponse(w, http.StatusCreated, response)
 }
 
-func EliminarReservaHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func EliminarReservaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	stockID := vars["stock_id"]
 	reservaID := vars["reserva_id"]
 
-	// Usar helper del paquete público para buscar auto
-	result := publicReserva.FindAutoByStockID(r.Context(), db, stockID)
-	if !result.Found {
-		publicReserva.WriteNotFoundResponse(w, "Auto no encontrado")
-		return
-	}
-	auto := result.Auto
-
-	// Buscar la reserva por ID
-	var indexToDelete int
-	found := false
-	for i := range auto.Reservas {
-		if auto.Reservas[i].ID == reservaID {
-			indexToDelete = i
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		publicReserva.WriteNotFoundResponse(w, "Reserva no encontrada")
-		return
-	}
-
-	// Eliminar la reserva
-	auto.Reservas = append(auto.Reservas[:indexToDelete], auto.Reservas[indexToDelete+1:]...)
-
-	// Usar helper del paquete público para actualizar
-	if err := publicReserva.UpdateAutoReservas(r.Context(), db, stockID, auto.Reservas); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusInternalServerError, "Error al actualizar las reservas")
+	if err := autos.EliminarReserva(r.Context(), stockID, reservaID); err != nil {
+		writeErrorReserva(w, r, stockID, err)
 		return
 	}
 
@@ -116,7 +81,7 @@ func EliminarReservaHandler(w http.ResponseWriter, r *http.Request, db database.
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
-func EditarReservaHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func EditarReservaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	stockID := vars["stock_id"]
@@ -124,54 +89,41 @@ func EditarReservaHandler(w http.ResponseWriter, r *http.Request, db database.Se
 
 	var nuevaReserva models.Reserva
 	if err := json.NewDecoder(r.Body).Decode(&nuevaReserva); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusBadRequest, "Error al decodificar el JSON")
-		return
-	}
-
-	// Usar helper del paquete público para buscar auto
-	result := publicReserva.FindAutoByStockID(r.Context(), db, stockID)
-	if !result.Found {
-		publicReserva.WriteNotFoundResponse(w, "Auto no encontrado")
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
-	auto := result.Auto
-
-	// Buscar la reserva por ID
-	var reservaEncontrada *models.Reserva
-	for i := range auto.Reservas {
-		if auto.Reservas[i].ID == reservaID {
-			reservaEncontrada = &auto.Reservas[i]
-			break
-		}
-	}
 
-	if reservaEncontrada == nil {
-		publicReserva.WriteNotFoundResponse(w, "Reserva no encontrada")
-		return
-	}
-
-	// Actualizar la reserva
-	reservaEncontrada.Nombre = nuevaReserva.Nombre
-	reservaEncontrada.Apellido = nuevaReserva.Apellido
-	reservaEncontrada.Telefono = nuevaReserva.Telefono
-	reservaEncontrada.Comentario = nuevaReserva.Comentario
-	reservaEncontrada.FechaHora = nuevaReserva.FechaHora
-
-	// Usar helper del paquete público para actualizar
-	if err := publicReserva.UpdateAutoReservas(r.Context(), db, stockID, auto.Reservas); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusInternalServerError, "Error al actualizar las reservas")
+	// Reemplazar solo esa reserva en el auto, de forma atómica
+	nuevaReserva.ID = reservaID
+	if err := autos.EditarReserva(r.Context(), stockID, nuevaReserva); err != nil {
+		writeErrorReserva(w, r, stockID, err)
 		return
 	}
 
 	response := map[string]interface{}{
 		"mensaje": "Reserva actualizada exitosamente",
-		"reserva": reservaEncontrada,
+		"reserva": nuevaReserva,
 	}
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
+// writeErrorReserva responde al error de una escritura de reservas del repositorio
+func writeErrorReserva(w http.ResponseWriter, r *http.Request, stockID string, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNoEncontrado):
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+	case errors.Is(err, repository.ErrReservaNoEncontrada):
+		helpers.ErrorResponse(w, r, helpers.CodigoReservaNoEncontrada, "Reserva no encontrada")
+	case errors.Is(err, repository.ErrReservaDuplicada):
+		helpers.ErrorResponse(w, r, helpers.CodigoReservaDuplicada, "Ya existe una reserva activa para este cliente y vehículo")
+	default:
+		logger.FromContext(r.Context()).Error("Error updating reservations", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al actualizar las reservas")
+	}
+}
+
 // ObtenerReservasHandler obtiene todas las reservaciones de un auto
-func ObtenerReservasHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func ObtenerReservasHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Obtener stock_id
@@ -180,14 +132,19 @@ func ObtenerReservasHandler(w http.ResponseWriter, r *http.Request, db database.
 
 	// Validar formato de stock_id
 	if err := models.ValidateStockID(stockID); err != nil {
-		publicReserva.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
 		return
 	}
 
 	// Usar helper del paquete público para buscar auto
-	result := publicReserva.FindAutoByStockID(r.Context(), db, stockID)
+	result := publicReserva.FindAutoByStockID(r.Context(), autos, stockID)
+	if result.Error != nil && !errors.Is(result.Error, repository.ErrNoEncontrado) {
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", result.Error)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
+		return
+	}
 	if !result.Found {
-		publicReserva.WriteNotFoundResponse(w, "Auto no encontrado")
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
 		return
 	}
 	auto := result.Auto
@@ -2,159 +2,209 @@ package public
 
 import (
 	"context"
-	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
+	"os"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
 	"go-gorilla-autos/internal/server/handlers/helpers"
 
+	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-func GetAutosHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// GetAutosHandler obtiene el catálogo público de autos, filtrado y paginado.
+// Por defecto pagina por número de página; con paginacion=cursor usa un cursor
+// estable para scroll infinito.
+func GetAutosHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
 	// Usar el parser de query params
 	qp := NewQueryParser(r)
 
-	filter := bson.M{}
-
-	// Filtrar por marca (case insensitive y coincidencias parciales)
-	if marca := qp.GetString("marca"); marca != "" {
-		filter["marca"] = bson.M{"$regex": "^" + marca, "$options": "i"}
+	filter := BuildAutosFilter(qp)
+	sort, err := BuildAutosSort(qp)
+	if err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
 	}
+	perPage := qp.GetPerPage()
+	page := qp.GetPage()
 
-	// Filtrar por modelo (case insensitive y coincidencias parciales)
-	if modelo := qp.GetString("modelo"); modelo != "" {
-		filter["modelo"] = bson.M{"$regex": "^" + modelo, "$options": "i"}
+	if invalidos := qp.Validate(ParametrosCatalogo); invalidos != nil {
+		writeParametrosInvalidos(w, r, invalidos)
+		return
 	}
 
-	// Filtrar por tipo de combustible
-	if combustible := qp.GetString("combustible"); combustible != "" {
-		filter["tipo_combustible"] = bson.M{"$regex": "^" + combustible, "$options": "i"}
+	// Con moneda_display los filtros de precio se expresan en esa moneda
+	moneda, tipos, ok := monedaDisplay(w, r, autos, qp)
+	if !ok {
+		return
 	}
-
-	// Filtrar por año
-	if año := qp.GetInt("año"); año > 0 {
-		filter["año"] = año
+	if moneda != "" {
+		tasa, _ := tipos.Tasa(moneda)
+		escalarFiltroPrecio(filter, tasa)
 	}
 
-	// Filtrar por kilometraje específico
-	if km := qp.GetInt("kilometraje"); km > 0 {
-		filter["kilometraje"] = km
+	if qp.GetString("paginacion") == "cursor" || qp.Has("cursor") {
+		getAutosPorCursor(w, r, autos, qp, filter, sort, perPage, tipos, moneda)
+		return
 	}
 
-	// Filtrar por rango de kilometraje
-	kmFilter := bson.M{}
-	if kmMin := qp.GetInt("km_min"); kmMin > 0 {
-		kmFilter["$gte"] = kmMin
-	}
-	if kmMax := qp.GetInt("km_max"); kmMax > 0 {
-		kmFilter["$lte"] = kmMax
-	}
-	if len(kmFilter) > 0 {
-		filter["kilometraje"] = kmFilter
+	total, err := autos.Count(r.Context(), repository.Consulta{Filtro: filter})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error counting autos", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los autos")
+		return
 	}
 
-	// Filtrar por precio específico
-	if precio := qp.GetFloat("precio"); precio > 0 {
-		filter["precio"] = precio
+	// Leer solo los campos públicos; con búsqueda de texto se ordena por relevancia
+	items, err := autos.FilterPublic(r.Context(), repository.Consulta{
+		Filtro:     filter,
+		Orden:      sort,
+		Relevancia: OrdenRelevancia(qp),
+		Saltar:     int64((page - 1) * perPage),
+		Limite:     int64(perPage),
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching autos from database", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los autos")
+		return
 	}
+	convertirAutos(items, tipos, moneda)
 
-	// Filtrar por rango de precios
-	precioFilter := bson.M{}
-	if precioMin := qp.GetFloat("precio_min"); precioMin > 0 {
-		precioFilter["$gte"] = precioMin
-	}
-	if precioMax := qp.GetFloat("precio_max"); precioMax > 0 {
-		precioFilter["$lte"] = precioMax
-	}
-	if len(precioFilter) > 0 {
-		filter["precio"] = precioFilter
-	}
+	helpers.JSONResponse(w, http.StatusOK, NewPagina(items, total, page, perPage))
+}
 
-	// Filtrar por autos destacados
-	if qp.GetString("destacado") == "true" {
-		filter["featured"] = true
+// getAutosPorCursor responde una página del catálogo usando paginación por cursor
+func getAutosPorCursor(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository, qp *QueryParser, filter bson.M, sort bson.D, perPage int, tipos models.TiposCambio, moneda string) {
+	// Se pide un elemento extra para saber si hay más resultados
+	consulta := repository.Consulta{Filtro: filter, Orden: sort, Limite: int64(perPage + 1)}
+	if token := qp.GetString("cursor"); token != "" {
+		desde, err := DecodeCursor(token, sort)
+		if err != nil {
+			helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+			return
+		}
+		consulta.Desde = desde
 	}
 
-	// Filtrar por autos con descuento
-	if qp.GetString("descuento") == "true" {
-		filter["descuento"] = bson.M{"$gt": 0}
+	items, err := autos.FilterPublic(r.Context(), consulta)
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching autos from database", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los autos")
+		return
 	}
 
-	// Configurar las opciones de ordenamiento
-	opts := options.Find()
-
-	// Ordenar por precio
-	if sortOrder := qp.GetSortOrder("sort_precio", "asc", "desc"); sortOrder != 0 {
-		opts.SetSort(bson.D{{Key: "precio", Value: sortOrder}})
+	hasMore := len(items) > perPage
+	if hasMore {
+		items = items[:perPage]
 	}
+	convertirAutos(items, tipos, moneda)
 
-	// Ordenar por fecha de publicación
-	if sortOrder := qp.GetSortOrder("sort_fecha", "viejo", "nuevo"); sortOrder != 0 {
-		opts.SetSort(bson.D{{Key: "created_at", Value: -sortOrder}})
+	pagina := PaginaCursor{Items: items, PerPage: perPage, HasMore: hasMore}
+	if hasMore {
+		next, err := encodeCursorAuto(sort, items[len(items)-1])
+		if err != nil {
+			logger.FromContext(r.Context()).Error("Error encoding cursor", "error", err)
+			helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al generar el cursor")
+			return
+		}
+		pagina.NextCursor = next
 	}
 
-	// Ordenar por kilometraje
-	if sortOrder := qp.GetSortOrder("sort_km", "menor", "mayor"); sortOrder != 0 {
-		opts.SetSort(bson.D{{Key: "kilometraje", Value: sortOrder}})
-	}
+	helpers.JSONResponse(w, http.StatusOK, pagina)
+}
 
-	collection := db.Collection("autos")
-	cursor, err := collection.Find(context.Background(), filter, opts)
-	if err != nil {
-		log.Printf("Error fetching autos from database: %v", err)
-		helpers.JSONErrorResponse(w, http.StatusInternalServerError, "Error al obtener los autos")
+// GetFeaturedAutosHandler obtiene los autos marcados como destacados
+func GetFeaturedAutosHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	moneda, tipos, ok := monedaDisplay(w, r, autos, NewQueryParser(r))
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var autos []models.Auto
-	if err = cursor.All(context.Background(), &autos); err != nil {
-		log.Printf("Error decoding autos: %v", err)
-		helpers.JSONErrorResponse(w, http.StatusInternalServerError, "Error al decodificar los autos")
+	// Filtrar solo autos destacados
+	destacados, err := autos.FilterPublic(r.Context(), repository.Consulta{Filtro: bson.M{"featured": true}})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching featured autos", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los autos destacados")
 		return
 	}
 
-	if len(autos) == 0 {
-		helpers.JSONErrorResponse(w, http.StatusNotFound, "No se encontraron autos con los filtros especificados")
+	if len(destacados) == 0 {
+		helpers.ErrorResponse(w, r, helpers.CodigoSinDestacados, "No hay autos destacados disponibles")
 		return
 	}
+	convertirAutos(destacados, tipos, moneda)
 
-	helpers.JSONResponse(w, http.StatusOK, autos)
+	helpers.JSONResponse(w, http.StatusOK, destacados)
 }
 
-// GetFeaturedAutosHandler obtiene los autos marcados como destacados
-func GetFeaturedAutosHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+// GetAutoHandler obtiene el detalle público de un auto por stock_id
+func GetAutoHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Filtrar solo autos destacados
-	filter := bson.M{"featured": true}
+	// Obtener stock_id
+	vars := mux.Vars(r)
+	stockID := vars["stock_id"]
 
-	collection := db.Collection("autos")
-	cursor, err := collection.Find(context.Background(), filter)
-	if err != nil {
-		log.Printf("Error fetching featured autos: %v", err)
-		helpers.JSONErrorResponse(w, http.StatusInternalServerError, "Error al obtener los autos destacados")
+	// Validar formato de stock_id
+	if err := models.ValidateStockID(stockID); err != nil {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, err.Error())
+		return
+	}
+
+	moneda, tipos, ok := monedaDisplay(w, r, autos, NewQueryParser(r))
+	if !ok {
 		return
 	}
-	defer cursor.Close(context.Background())
 
-	var autos []models.Auto
-	if err = cursor.All(context.Background(), &autos); err != nil {
-		log.Printf("Error decoding featured autos: %v", err)
-		helpers.JSONErrorResponse(w, http.StatusInternalServerError, "Error al decodificar los autos")
+	auto, err := FindAutoPublicoByStockID(r.Context(), autos, stockID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoEncontrado) {
+			helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
+			return
+		}
+		logger.FromContext(r.Context()).Error("Error fetching auto", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener el auto")
 		return
 	}
 
-	if len(autos) == 0 {
-		helpers.JSONErrorResponse(w, http.StatusNotFound, "No hay autos destacados disponibles")
+	// Los autos vendidos se ocultan del catálogo si así está configurado
+	if auto.Estado == models.EstadoVendido && ocultarVendidos() {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
 		return
 	}
+	if moneda != "" {
+		auto.ConvertirPrecios(tipos, moneda)
+	}
+
+	helpers.JSONResponse(w, http.StatusOK, auto)
+}
+
+// ocultarVendidos indica si los autos vendidos deben responder 404 en el detalle público
+func ocultarVendidos() bool {
+	return os.Getenv("OCULTAR_VENDIDOS") == "true"
+}
+
+// FindAutoPublicoByStockID busca un auto por stock_id leyendo solo los campos públicos
+// y calculando su precio efectivo. Devuelve repository.ErrNoEncontrado si no existe.
+func FindAutoPublicoByStockID(ctx context.Context, autos repository.AutoRepository, stockID string) (models.AutoPublico, error) {
+	encontrados, err := autos.FilterPublic(ctx, repository.Consulta{Filtro: bson.M{"stock_id": stockID}, Limite: 1})
+	if err != nil {
+		return models.AutoPublico{}, err
+	}
+	if len(encontrados) == 0 {
+		return models.AutoPublico{}, repository.ErrNoEncontrado
+	}
+	return encontrados[0], nil
+}
 
-	helpers.JSONResponse(w, http.StatusOK, autos)
+// writeParametrosInvalidos responde 400 con la lista de parámetros rechazados en modo estricto
+func writeParametrosInvalidos(w http.ResponseWriter, r *http.Request, invalidos *ParametrosInvalidosError) {
+	helpers.ErrorResponseConDetalles(w, r, helpers.CodigoParametroInvalido, invalidos.Error(), invalidos)
 }
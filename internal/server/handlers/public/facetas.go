@@ -0,0 +1,125 @@
+package public
+
+import (
+	"net/http"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+)
+
+// facetasCategoricas son los campos del catálogo que se cuentan por valor
+var facetasCategoricas = []string{
+	"marca",
+	"modelo",
+	"tipo_combustible",
+	"transmision",
+	"traccion",
+	"ciudad",
+	"sucursal",
+}
+
+// limitesAño son los límites de los rangos de año; cada rango incluye el límite inferior
+var limitesAño = []float64{1990, 2000, 2005, 2010, 2015, 2020, 2025, 2100}
+
+// limitesPrecio son los límites de los rangos de precio en la moneda de referencia; cada
+// rango incluye el límite inferior
+var limitesPrecio = []float64{0, 5000, 10000, 15000, 20000, 30000, 50000, 100000}
+
+// FacetaValor es la cantidad de autos para un valor de una faceta
+type FacetaValor struct {
+	Valor    string `json:"valor"`
+	Cantidad int    `json:"cantidad"`
+}
+
+// FacetaRango es la cantidad de autos dentro de un rango [desde, hasta)
+type FacetaRango struct {
+	Desde    interface{} `json:"desde"`
+	Hasta    interface{} `json:"hasta,omitempty"`
+	Cantidad int         `json:"cantidad"`
+}
+
+// GetFacetasHandler devuelve los conteos por faceta para los filtros actuales del catálogo.
+// Cada faceta ignora su propio filtro para que se vean las alternativas disponibles.
+func GetFacetasHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
+	w.Header().Set("Content-Type", "application/json")
+
+	qp := NewQueryParser(r)
+	filter := BuildAutosFilter(qp)
+	if invalidos := qp.Validate(ParametrosCatalogo); invalidos != nil {
+		writeParametrosInvalidos(w, r, invalidos)
+		return
+	}
+	moneda, tipos, ok := monedaDisplay(w, r, autos, qp)
+	if !ok {
+		return
+	}
+	if moneda != "" {
+		tasa, _ := tipos.Tasa(moneda)
+		escalarFiltroPrecio(filter, tasa)
+	}
+	resultado, err := autos.Facets(r.Context(), repository.ConsultaFacetas{
+		Filtro:      filter,
+		Categoricas: facetasCategoricas,
+		Rangos: []repository.Rango{
+			{Nombre: "año", Campo: "año", Limites: limitesAño},
+			{Nombre: "precio", Campo: "precio_normalizado", Limites: limitesPrecio},
+		},
+	})
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error aggregating facets", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener las facetas")
+		return
+	}
+
+	response := map[string]interface{}{
+		"total": resultado.Total,
+	}
+	for _, campo := range facetasCategoricas {
+		valores := []FacetaValor{}
+		for _, conteo := range resultado.Conteo[campo] {
+			valor, _ := conteo.Valor.(string)
+			valores = append(valores, FacetaValor{Valor: valor, Cantidad: conteo.Cantidad})
+		}
+		response[campo] = valores
+	}
+	response["año"] = rangos(resultado.Conteo["año"], limitesAño)
+	response["precio"] = rangos(resultado.Conteo["precio"], limitesPrecio)
+	response["moneda_precio"] = models.MonedaReferencia
+
+	helpers.JSONResponse(w, http.StatusOK, response)
+}
+
+// rangos arma la respuesta de una faceta por rangos completando el límite superior de cada rango
+func rangos(conteo []repository.ConteoFaceta, limites []float64) []FacetaRango {
+	rangos := []FacetaRango{}
+	for _, c := range conteo {
+		rango := FacetaRango{Desde: c.Valor, Cantidad: c.Cantidad}
+		if desde, ok := toFloat(c.Valor); ok {
+			for j := 0; j < len(limites)-1; j++ {
+				if limites[j] == desde {
+					rango.Desde = limites[j]
+					rango.Hasta = limites[j+1]
+					break
+				}
+			}
+		}
+		rangos = append(rangos, rango)
+	}
+	return rangos
+}
+
+// toFloat convierte un número decodificado de BSON a float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,266 @@
+package public
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// filtroTexto describe un filtro de texto del catálogo
+type filtroTexto struct {
+	param   string   // nombre del query param
+	alias   []string // nombres alternativos del query param
+	campo   string   // campo en la base
+	prefijo bool     // si coincide por prefijo en lugar de por valor completo
+}
+
+// filtrosTexto son los filtros de texto del catálogo. Todos ignoran mayúsculas y acentos.
+var filtrosTexto = []filtroTexto{
+	{param: "marca", campo: "marca", prefijo: true},
+	{param: "modelo", campo: "modelo", prefijo: true},
+	{param: "tipo_combustible", alias: []string{"combustible"}, campo: "tipo_combustible", prefijo: true},
+	{param: "transmision", campo: "transmision"},
+	{param: "traccion", campo: "traccion"},
+	{param: "ciudad", campo: "ciudad"},
+	{param: "sucursal", campo: "sucursal"},
+	{param: "tipo_venta", campo: "tipo_venta"},
+	{param: "moneda", campo: "moneda"},
+	{param: "estado", campo: "estado"},
+}
+
+// ParametrosCatalogo son los query params que entiende el catálogo; en modo estricto
+// cualquier otro parámetro se rechaza
+var ParametrosCatalogo = func() []string {
+	params := []string{
+		"q", "año", "año_min", "año_max", "kilometraje", "km_min", "km_max",
+		"precio", "precio_min", "precio_max", "destacado", "descuento", "bajo_precio_dias",
+		"sort", "sort_precio", "sort_fecha", "sort_km", "moneda_display",
+		"page", "per_page", "paginacion", "cursor", "strict",
+	}
+	for _, f := range filtrosTexto {
+		for _, param := range append([]string{f.param}, f.alias...) {
+			params = append(params, param, "-"+param)
+		}
+	}
+	return params
+}()
+
+// BuildAutosFilter arma el filtro de MongoDB del catálogo a partir de los query params
+func BuildAutosFilter(qp *QueryParser) bson.M {
+	filter := bson.M{}
+
+	// Búsqueda de texto libre sobre marca, modelo, versión, equipamiento y características.
+	// Sin distinguir acentos: "automática" y "automatica" coinciden.
+	if q := qp.GetString("q"); q != "" {
+		filter["$text"] = bson.M{
+			"$search":             q,
+			"$language":           "spanish",
+			"$caseSensitive":      false,
+			"$diacriticSensitive": false,
+		}
+	}
+
+	// Filtros de texto: admiten varios valores separados por coma y exclusiones
+	// con el prefijo "-" en el nombre del parámetro (-tipo_combustible=diesel)
+	for _, f := range filtrosTexto {
+		incluir := qp.GetList(f.param)
+		for _, alias := range f.alias {
+			incluir = append(incluir, qp.GetList(alias)...)
+		}
+		excluir := qp.GetList("-" + f.param)
+		for _, alias := range f.alias {
+			excluir = append(excluir, qp.GetList("-"+alias)...)
+		}
+		if cond := condicionTexto(incluir, excluir, f.prefijo); cond != nil {
+			filter[f.campo] = cond
+		}
+	}
+
+	// Filtrar por año
+	if año := qp.GetInt("año"); año > 0 {
+		filter["año"] = año
+	}
+
+	// Filtrar por rango de años
+	añoFilter := bson.M{}
+	if añoMin := qp.GetInt("año_min"); añoMin > 0 {
+		añoFilter["$gte"] = añoMin
+	}
+	if añoMax := qp.GetInt("año_max"); añoMax > 0 {
+		añoFilter["$lte"] = añoMax
+	}
+	if len(añoFilter) > 0 {
+		filter["año"] = añoFilter
+	}
+
+	// Filtrar por kilometraje específico
+	if km := qp.GetInt("kilometraje"); km > 0 {
+		filter["kilometraje"] = km
+	}
+
+	// Filtrar por rango de kilometraje
+	kmFilter := bson.M{}
+	if kmMin := qp.GetInt("km_min"); kmMin > 0 {
+		kmFilter["$gte"] = kmMin
+	}
+	if kmMax := qp.GetInt("km_max"); kmMax > 0 {
+		kmFilter["$lte"] = kmMax
+	}
+	if len(kmFilter) > 0 {
+		filter["kilometraje"] = kmFilter
+	}
+
+	// Filtrar por precio específico. Los precios se comparan en la moneda de referencia
+	// para que los autos en distintas monedas sean comparables.
+	if precio := qp.GetFloat("precio"); precio > 0 {
+		filter["precio_normalizado"] = precio
+	}
+
+	// Filtrar por rango de precios
+	precioFilter := bson.M{}
+	if precioMin := qp.GetFloat("precio_min"); precioMin > 0 {
+		precioFilter["$gte"] = precioMin
+	}
+	if precioMax := qp.GetFloat("precio_max"); precioMax > 0 {
+		precioFilter["$lte"] = precioMax
+	}
+	if len(precioFilter) > 0 {
+		filter["precio_normalizado"] = precioFilter
+	}
+
+	// Filtrar por autos destacados
+	if qp.GetString("destacado") == "true" {
+		filter["featured"] = true
+	}
+
+	// Filtrar por autos con descuento
+	if qp.GetString("descuento") == "true" {
+		filter["descuento"] = bson.M{"$gt": 0}
+	}
+
+	// Filtrar por autos que bajaron de precio en los últimos N días
+	if dias := qp.GetInt("bajo_precio_dias"); dias > 0 {
+		filter["bajo_de_precio"] = true
+		filter["bajo_de_precio_en"] = bson.M{"$gte": time.Now().AddDate(0, 0, -dias)}
+	}
+
+	return filter
+}
+
+// vocales es la clase de expresión regular de cada vocal, con y sin acento. Los valores
+// de lista cerrada se guardan con acento ("automática", "diésel") y se buscan sin él.
+var vocales = map[rune]string{
+	'a': "[aá]", 'á': "[aá]",
+	'e': "[eé]", 'é': "[eé]",
+	'i': "[ií]", 'í': "[ií]",
+	'o': "[oó]", 'ó': "[oó]",
+	'u': "[uúü]", 'ú': "[uúü]", 'ü': "[uúü]",
+}
+
+// patronSinAcentos escapa el valor para usarlo en una expresión regular y hace que cada
+// vocal coincida con o sin acento
+func patronSinAcentos(valor string) string {
+	var patron strings.Builder
+	for _, r := range strings.ToLower(valor) {
+		if clase, ok := vocales[r]; ok {
+			patron.WriteString(clase)
+		} else {
+			patron.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return patron.String()
+}
+
+// condicionTexto arma la condición de un filtro de texto con los valores a incluir y a
+// excluir. Los valores se escapan para que no se interpreten como expresiones regulares.
+func condicionTexto(incluir []string, excluir []string, prefijo bool) bson.M {
+	patron := func(valor string) primitive.Regex {
+		p := "^" + patronSinAcentos(valor)
+		if !prefijo {
+			p += "$"
+		}
+		return primitive.Regex{Pattern: p, Options: "i"}
+	}
+
+	cond := bson.M{}
+	if len(incluir) > 0 {
+		in := bson.A{}
+		for _, valor := range incluir {
+			in = append(in, patron(valor))
+		}
+		cond["$in"] = in
+	}
+	if len(excluir) > 0 {
+		nin := bson.A{}
+		for _, valor := range excluir {
+			nin = append(nin, patron(valor))
+		}
+		cond["$nin"] = nin
+	}
+	if len(cond) == 0 {
+		return nil
+	}
+	return cond
+}
+
+// camposOrdenables es la lista blanca de campos por los que se puede ordenar con sort=,
+// con sus alias hacia el nombre del campo en la base
+var camposOrdenables = map[string]string{
+	"precio":      "precio_normalizado",
+	"created_at":  "created_at",
+	"fecha":       "created_at",
+	"updated_at":  "updated_at",
+	"kilometraje": "kilometraje",
+	"km":          "kilometraje",
+	"año":         "año",
+	"marca":       "marca",
+	"modelo":      "modelo",
+	"stock_id":    "stock_id",
+}
+
+// BuildAutosSort arma el orden del catálogo a partir de sort=precio,-created_at,...
+// Los parámetros sort_precio, sort_fecha y sort_km se mantienen como alias y se
+// agregan después de los campos de sort=. Siempre desempata por stock_id para que
+// el orden sea estable.
+func BuildAutosSort(qp *QueryParser) (bson.D, error) {
+	sort, err := qp.GetSort("sort", camposOrdenables)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ordenar por precio
+	if sortOrder := qp.GetSortOrder("sort_precio", "asc", "desc"); sortOrder != 0 {
+		sort = appendSort(sort, "precio_normalizado", sortOrder)
+	}
+
+	// Ordenar por fecha de publicación
+	if sortOrder := qp.GetSortOrder("sort_fecha", "viejo", "nuevo"); sortOrder != 0 {
+		sort = appendSort(sort, "created_at", -sortOrder)
+	}
+
+	// Ordenar por kilometraje
+	if sortOrder := qp.GetSortOrder("sort_km", "menor", "mayor"); sortOrder != 0 {
+		sort = appendSort(sort, "kilometraje", sortOrder)
+	}
+
+	return appendSort(sort, "stock_id", 1), nil
+}
+
+// OrdenRelevancia indica si el catálogo se ordena primero por el puntaje de la búsqueda
+// de texto: cuando hay q y no se pidió un orden explícito
+func OrdenRelevancia(qp *QueryParser) bool {
+	return qp.Has("q") && !qp.Has("sort") && !qp.Has("sort_precio") && !qp.Has("sort_fecha") && !qp.Has("sort_km")
+}
+
+// appendSort agrega un campo al orden si todavía no está incluido
+func appendSort(sort bson.D, campo string, order int) bson.D {
+	for _, e := range sort {
+		if e.Key == campo {
+			return sort
+		}
+	}
+	return append(sort, bson.E{Key: campo, Value: order})
+}
@@ -0,0 +1,58 @@
+package public
+
+import (
+	"net/http"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// monedaDisplay lee moneda_display y, si se pidió, carga los tipos de cambio para
+// convertir los precios. Si falla escribe la respuesta.
+func monedaDisplay(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository, qp *QueryParser) (string, models.TiposCambio, bool) {
+	moneda := models.NormalizarMoneda(qp.GetString("moneda_display"))
+	if moneda == "" {
+		return "", nil, true
+	}
+
+	tipos, err := autos.TiposCambio(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error fetching exchange rates", "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al obtener los tipos de cambio")
+		return "", nil, false
+	}
+	if _, ok := tipos.Tasa(moneda); !ok {
+		helpers.ErrorResponse(w, r, helpers.CodigoParametroInvalido, "moneda_display inválida: no hay tipo de cambio para "+moneda)
+		return "", nil, false
+	}
+	return moneda, tipos, true
+}
+
+// escalarFiltroPrecio convierte a la moneda de referencia los precios del filtro, que
+// con moneda_display se expresan en esa moneda
+func escalarFiltroPrecio(filter bson.M, tasa float64) {
+	switch cond := filter["precio_normalizado"].(type) {
+	case float64:
+		filter["precio_normalizado"] = cond / tasa
+	case bson.M:
+		for op, valor := range cond {
+			if v, ok := valor.(float64); ok {
+				cond[op] = v / tasa
+			}
+		}
+	}
+}
+
+// convertirAutos agrega a cada auto sus precios en la moneda pedida
+func convertirAutos(autos []models.AutoPublico, tipos models.TiposCambio, moneda string) {
+	if moneda == "" {
+		return
+	}
+	for i := range autos {
+		autos[i].ConvertirPrecios(tipos, moneda)
+	}
+}
@@ -0,0 +1,124 @@
+package public
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"go-gorilla-autos/internal/database/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// PorPaginaDefault es la cantidad de autos por página si no se indica per_page
+	PorPaginaDefault = 20
+	// PorPaginaMax es el máximo de autos por página que se permite pedir
+	PorPaginaMax = 100
+)
+
+var errCursorInvalido = errors.New("cursor inválido")
+
+// Pagina es la respuesta paginada por número de página
+type Pagina struct {
+	Items   interface{} `json:"items"`
+	Total   int64       `json:"total"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"per_page"`
+	Pages   int         `json:"pages"`
+}
+
+// PaginaCursor es la respuesta paginada por cursor, pensada para scroll infinito
+type PaginaCursor struct {
+	Items      interface{} `json:"items"`
+	PerPage    int         `json:"per_page"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	HasMore    bool        `json:"has_more"`
+}
+
+// NewPagina arma la respuesta paginada calculando la cantidad de páginas
+func NewPagina(items interface{}, total int64, page int, perPage int) Pagina {
+	pages := int((total + int64(perPage) - 1) / int64(perPage))
+	return Pagina{
+		Items:   items,
+		Total:   total,
+		Page:    page,
+		PerPage: perPage,
+		Pages:   pages,
+	}
+}
+
+// cursorPayload es el contenido del cursor: los campos de orden y los valores del último auto devuelto
+type cursorPayload struct {
+	Keys   []string `bson:"k"`
+	Values bson.A   `bson:"v"`
+}
+
+// EncodeCursor genera el cursor opaco que apunta al documento dado para el orden indicado
+func EncodeCursor(sort bson.D, doc bson.Raw) (string, error) {
+	payload := cursorPayload{}
+	for _, e := range sort {
+		value, err := doc.LookupErr(e.Key)
+		if err != nil {
+			return "", fmt.Errorf("el documento no tiene el campo de orden %s: %w", e.Key, err)
+		}
+		payload.Keys = append(payload.Keys, e.Key)
+		payload.Values = append(payload.Values, value)
+	}
+
+	data, err := bson.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// encodeCursorAuto genera el cursor que apunta al auto dado para el orden indicado
+func encodeCursorAuto(sort bson.D, auto models.AutoPublico) (string, error) {
+	doc, err := bson.Marshal(auto)
+	if err != nil {
+		return "", err
+	}
+	return EncodeCursor(sort, doc)
+}
+
+// DecodeCursor convierte un cursor en el filtro que selecciona los documentos
+// posteriores al último devuelto, respetando el orden indicado
+func DecodeCursor(token string, sort bson.D) (bson.M, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errCursorInvalido
+	}
+
+	var payload cursorPayload
+	if err := bson.Unmarshal(data, &payload); err != nil {
+		return nil, errCursorInvalido
+	}
+
+	// El cursor solo es válido para el mismo orden con el que se generó
+	if len(payload.Keys) != len(sort) || len(payload.Values) != len(sort) {
+		return nil, errCursorInvalido
+	}
+	for i, e := range sort {
+		if payload.Keys[i] != e.Key {
+			return nil, errCursorInvalido
+		}
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... según la dirección de cada campo
+	or := bson.A{}
+	for i, e := range sort {
+		cond := bson.M{}
+		for j := 0; j < i; j++ {
+			cond[sort[j].Key] = payload.Values[j]
+		}
+		op := "$gt"
+		if order, ok := e.Value.(int); ok && order < 0 {
+			op = "$lt"
+		}
+		cond[e.Key] = bson.M{op: payload.Values[i]}
+		or = append(or, cond)
+	}
+
+	return bson.M{"$or": or}, nil
+}
@@ -1,13 +1,42 @@
 package public
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // QueryParser encapsula la lógica de parseo de query parameters
 type QueryParser struct {
 	Query map[string]string
+
+	// Strict indica si los parámetros desconocidos o mal formados deben rechazarse.
+	// Se activa con strict=true.
+	Strict bool
+
+	// invalidos registra los parámetros presentes cuyo valor no se pudo interpretar
+	invalidos map[string]bool
+}
+
+// ParametrosInvalidosError describe los parámetros rechazados en modo estricto
+type ParametrosInvalidosError struct {
+	Desconocidos []string `json:"desconocidos,omitempty"`
+	Invalidos    []string `json:"invalidos,omitempty"`
+}
+
+func (e *ParametrosInvalidosError) Error() string {
+	partes := []string{}
+	if len(e.Desconocidos) > 0 {
+		partes = append(partes, "parámetros desconocidos: "+strings.Join(e.Desconocidos, ", "))
+	}
+	if len(e.Invalidos) > 0 {
+		partes = append(partes, "parámetros con valor inválido: "+strings.Join(e.Invalidos, ", "))
+	}
+	return strings.Join(partes, "; ")
 }
 
 // NewQueryParser crea un nuevo parser a partir de la request
@@ -18,7 +47,25 @@ func NewQueryParser(r *http.Request) *QueryParser {
 			query[key] = values[0]
 		}
 	}
-	return &QueryParser{Query: query}
+	return &QueryParser{
+		Query:     query,
+		Strict:    query["strict"] == "true",
+		invalidos: map[string]bool{},
+	}
+}
+
+// NewQueryParserFromMap crea un parser a partir de parámetros ya guardados, por ejemplo
+// el filtro de una campaña
+func NewQueryParserFromMap(params map[string]string) *QueryParser {
+	query := make(map[string]string, len(params))
+	for key, value := range params {
+		query[key] = value
+	}
+	return &QueryParser{
+		Query:     query,
+		Strict:    query["strict"] == "true",
+		invalidos: map[string]bool{},
+	}
 }
 
 // GetString obtiene un string del query param, o empty string si no existe
@@ -26,19 +73,34 @@ func (qp *QueryParser) GetString(key string) string {
 	return qp.Query[key]
 }
 
-// GetInt obtiene un int del query param, o 0 si no existe o es inválido
+// GetList obtiene los valores separados por coma de un query param, sin los vacíos
+func (qp *QueryParser) GetList(key string) []string {
+	valores := []string{}
+	for _, valor := range strings.Split(qp.GetString(key), ",") {
+		if valor = strings.TrimSpace(valor); valor != "" {
+			valores = append(valores, valor)
+		}
+	}
+	return valores
+}
+
+// GetInt obtiene un int del query param, o 0 si no existe o es inválido.
+// Los valores inválidos quedan registrados para el modo estricto.
 func (qp *QueryParser) GetInt(key string) int {
 	val, err := strconv.Atoi(qp.Query[key])
 	if err != nil {
+		qp.marcarInvalido(key)
 		return 0
 	}
 	return val
 }
 
-// GetFloat obtiene un float64 del query param, o 0 si no existe o es inválido
+// GetFloat obtiene un float64 del query param, o 0 si no existe o es inválido.
+// Los valores inválidos quedan registrados para el modo estricto.
 func (qp *QueryParser) GetFloat(key string) float64 {
 	val, err := strconv.ParseFloat(qp.Query[key], 64)
 	if err != nil {
+		qp.marcarInvalido(key)
 		return 0
 	}
 	return val
@@ -59,6 +121,99 @@ func (qp *QueryParser) GetSortOrder(key string, ascValue string, descValue strin
 	case descValue:
 		return -1
 	default:
+		qp.marcarInvalido(key)
 		return 0
 	}
 }
+
+// marcarInvalido registra un parámetro presente con un valor que no se pudo interpretar
+func (qp *QueryParser) marcarInvalido(key string) {
+	if qp.Has(key) {
+		qp.invalidos[key] = true
+	}
+}
+
+// Validate verifica en modo estricto que todos los parámetros estén en permitidos y
+// que los valores leídos hasta ahora sean válidos. Debe llamarse después de leer los
+// parámetros. Fuera del modo estricto siempre devuelve nil.
+func (qp *QueryParser) Validate(permitidos []string) *ParametrosInvalidosError {
+	if !qp.Strict {
+		return nil
+	}
+
+	conocidos := make(map[string]bool, len(permitidos))
+	for _, key := range permitidos {
+		conocidos[key] = true
+	}
+
+	result := &ParametrosInvalidosError{}
+	for key := range qp.Query {
+		if !conocidos[key] {
+			result.Desconocidos = append(result.Desconocidos, key)
+		}
+	}
+	for key := range qp.invalidos {
+		result.Invalidos = append(result.Invalidos, key)
+	}
+	if len(result.Desconocidos) == 0 && len(result.Invalidos) == 0 {
+		return nil
+	}
+
+	sort.Strings(result.Desconocidos)
+	sort.Strings(result.Invalidos)
+	return result
+}
+
+// GetSort parsea un orden compuesto con formato "campo,-campo2" (el prefijo "-" indica
+// orden descendente). Solo acepta los campos de permitidos, que mapea alias a nombres
+// de campo; devuelve error si algún campo no está permitido.
+func (qp *QueryParser) GetSort(key string, permitidos map[string]string) (bson.D, error) {
+	orden := bson.D{}
+	if !qp.Has(key) {
+		return orden, nil
+	}
+
+	vistos := map[string]bool{}
+	for _, token := range strings.Split(qp.GetString(key), ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+
+		order := 1
+		if strings.HasPrefix(token, "-") {
+			order = -1
+			token = token[1:]
+		} else if strings.HasPrefix(token, "+") {
+			token = token[1:]
+		}
+
+		campo, ok := permitidos[token]
+		if !ok {
+			return nil, fmt.Errorf("no se puede ordenar por %q", token)
+		}
+		if vistos[campo] {
+			continue
+		}
+		vistos[campo] = true
+		orden = append(orden, bson.E{Key: campo, Value: order})
+	}
+	return orden, nil
+}
+
+// GetPage obtiene el número de página (page), 1 si no existe o es inválido
+func (qp *QueryParser) GetPage() int {
+	if page := qp.GetInt("page"); page > 0 {
+		return page
+	}
+	return 1
+}
+
+// GetPerPage obtiene la cantidad de elementos por página (per_page), acotada a PorPaginaMax
+func (qp *QueryParser) GetPerPage() int {
+	perPage := qp.GetInt("per_page")
+	if perPage <= 0 {
+		return PorPaginaDefault
+	}
+	return min(perPage, PorPaginaMax)
+}
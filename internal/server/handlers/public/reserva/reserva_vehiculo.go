@@ -2,23 +2,26 @@ package reserva
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
-	"go-gorilla-autos/internal/database"
 	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/logger"
+	"go-gorilla-autos/internal/server/handlers/helpers"
 
 	"github.com/gorilla/mux"
 )
 
 // CrearReservaHandler maneja la creación de una nueva reserva
-func CrearReservaHandler(w http.ResponseWriter, r *http.Request, db database.Service) {
+func CrearReservaHandler(w http.ResponseWriter, r *http.Request, autos repository.AutoRepository) {
 	w.Header().Set("Content-Type", "application/json")
 	vars := mux.Vars(r)
 	stockID := vars["stock_id"]
 
 	var reserva models.Reserva
 	if err := json.NewDecoder(r.Body).Decode(&reserva); err != nil {
-		WriteErrorResponse(w, http.StatusBadRequest, "Error al decodificar el JSON")
+		helpers.ErrorResponse(w, r, helpers.CodigoJSONInvalido, "Error al decodificar el JSON")
 		return
 	}
 
@@ -27,37 +30,29 @@ func CrearReservaHandler(w http.ResponseWriter, r *http.Request, db database.Ser
 
 	// Validaciones básicas
 	if reserva.Nombre == "" || reserva.Apellido == "" {
-		WriteErrorResponse(w, http.StatusBadRequest, "Nombre y apellido son requeridos")
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Nombre y apellido son requeridos")
 		return
 	}
 
 	if reserva.FechaHora.IsZero() {
-		WriteErrorResponse(w, http.StatusBadRequest, "Fecha y hora de reserva son requeridas")
+		helpers.ErrorResponse(w, r, helpers.CodigoValidacion, "Fecha y hora de reserva son requeridas")
 		return
 	}
 
-	// Buscar el auto usando el helper
-	result := FindAutoByStockID(r.Context(), db, stockID)
-	if !result.Found {
-		WriteNotFoundResponse(w, "Auto no encontrado")
+	// La reserva se agrega de forma atómica; el repositorio rechaza un segundo turno del
+	// mismo cliente para el auto
+	auto, err := autos.AgregarReserva(r.Context(), stockID, reserva)
+	if errors.Is(err, repository.ErrNoEncontrado) {
+		helpers.ErrorResponse(w, r, helpers.CodigoAutoNoEncontrado, "Auto no encontrado")
 		return
 	}
-	auto := result.Auto
-
-	// Verificar si el cliente ya tiene una reserva para este auto
-	for _, existingReserva := range auto.Reservas {
-		if existingReserva.Nombre == reserva.Nombre && existingReserva.Apellido == reserva.Apellido {
-			WriteErrorResponse(w, http.StatusConflict, "Ya existe una reserva activa para este cliente y vehículo")
-			return
-		}
+	if errors.Is(err, repository.ErrReservaDuplicada) {
+		helpers.ErrorResponse(w, r, helpers.CodigoReservaDuplicada, "Ya existe una reserva activa para este cliente y vehículo")
+		return
 	}
-
-	// Agregar la nueva reserva
-	auto.Reservas = append(auto.Reservas, reserva)
-
-	// Actualizar usando el helper
-	if err := UpdateAutoReservas(r.Context(), db, stockID, auto.Reservas); err != nil {
-		WriteErrorResponse(w, http.StatusInternalServerError, "Error al actualizar las reservas")
+	if err != nil {
+		logger.FromContext(r.Context()).Error("Error updating reservations", "stock_id", stockID, "error", err)
+		helpers.ErrorResponse(w, r, helpers.CodigoInterno, "Error al actualizar las reservas")
 		return
 	}
 
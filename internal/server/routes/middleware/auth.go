@@ -4,6 +4,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"go-gorilla-autos/internal/server/handlers/helpers"
 )
 
 func AuthMiddlewareFunc(next http.Handler) http.Handler {
@@ -17,14 +19,14 @@ func AuthMiddlewareFunc(next http.Handler) http.Handler {
 		// Obtener el token de autorización
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			http.Error(w, "Se requiere autorización", http.StatusUnauthorized)
+			helpers.ErrorResponse(w, r, helpers.CodigoNoAutorizado, "Se requiere autorización")
 			return
 		}
 
 		// Extraer el token (Bearer Token)
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Formato de autorización inválido", http.StatusUnauthorized)
+			helpers.ErrorResponse(w, r, helpers.CodigoNoAutorizado, "Formato de autorización inválido")
 			return
 		}
 
@@ -32,7 +34,7 @@ func AuthMiddlewareFunc(next http.Handler) http.Handler {
 		expectedToken := os.Getenv("AUTH_KEY")
 
 		if token != expectedToken {
-			http.Error(w, "Código de autorización inválido", http.StatusUnauthorized)
+			helpers.ErrorResponse(w, r, helpers.CodigoNoAutorizado, "Código de autorización inválido")
 			return
 		}
 
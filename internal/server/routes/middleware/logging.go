@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"time"
+
+	"go-gorilla-autos/internal/logger"
+)
+
+// HeaderRequestID es el header con el que se recibe y se devuelve el ID de la solicitud
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDValido limita los request ID recibidos para que no se puedan inyectar en los logs
+var requestIDValido = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// LoggingMiddleware asigna a cada solicitud un request ID, o usa el que llega en
+// X-Request-ID, y lo devuelve en la respuesta. Los handlers obtienen con
+// logger.FromContext un logger que ya incluye el request ID. Al terminar registra
+// método, ruta, código, latencia y bytes de la respuesta.
+func LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inicio := time.Now()
+
+		requestID := r.Header.Get(HeaderRequestID)
+		if !requestIDValido.MatchString(requestID) {
+			requestID = nuevoRequestID()
+		}
+		w.Header().Set(HeaderRequestID, requestID)
+
+		l := slog.Default().With("request_id", requestID)
+		respuesta := &respuestaConEstado{ResponseWriter: w}
+		next.ServeHTTP(respuesta, r.WithContext(logger.NewContext(r.Context(), l, requestID)))
+
+		status := respuesta.Status()
+		nivel := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			nivel = slog.LevelError
+		}
+		l.Log(r.Context(), nivel, "request",
+			"method", r.Method,
+			"route", plantillaRuta(r),
+			"path", r.URL.Path,
+			"status", status,
+			"latency_ms", float64(time.Since(inicio).Microseconds())/1000,
+			"bytes", respuesta.bytes,
+		)
+	})
+}
+
+// nuevoRequestID genera un ID aleatorio de 16 caracteres hexadecimales
+func nuevoRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "sin-id"
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-gorilla-autos/internal/logger"
+
+	"github.com/gorilla/mux"
+)
+
+// registrarLogs redirige el logger por defecto a un buffer mientras dura el test
+func registrarLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	anterior := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(anterior) })
+	return &buf
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	buf := registrarLogs(t)
+
+	var idHandler string
+	r := mux.NewRouter()
+	r.Use(LoggingMiddleware)
+	r.HandleFunc("/autos/{stockID}", func(w http.ResponseWriter, r *http.Request) {
+		idHandler = logger.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+		w.Write([]byte("hola"))
+	})
+
+	req := httptest.NewRequest("GET", "/autos/T0001", nil)
+	req.Header.Set(HeaderRequestID, "abc-123")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(HeaderRequestID); got != "abc-123" || idHandler != "abc-123" {
+		t.Errorf("request ID en la respuesta = %q, en el handler = %q, se esperaba abc-123", got, idHandler)
+	}
+
+	var linea struct {
+		Msg       string `json:"msg"`
+		RequestID string `json:"request_id"`
+		Route     string `json:"route"`
+		Status    int    `json:"status"`
+		Bytes     int    `json:"bytes"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &linea); err != nil {
+		t.Fatalf("log inválido %q: %v", buf.String(), err)
+	}
+	if linea.Msg != "request" || linea.RequestID != "abc-123" || linea.Route != "/autos/{stockID}" ||
+		linea.Status != http.StatusTeapot || linea.Bytes != 4 {
+		t.Errorf("log = %+v", linea)
+	}
+}
+
+func TestLoggingMiddlewareGeneraRequestID(t *testing.T) {
+	registrarLogs(t)
+
+	r := mux.NewRouter()
+	r.Use(LoggingMiddleware)
+	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	for _, recibido := range []string{"", "con espacios\ny saltos"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(HeaderRequestID, recibido)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(HeaderRequestID)
+		if got == "" || got == recibido || !requestIDValido.MatchString(got) {
+			t.Errorf("request ID recibido %q: se devolvió %q", recibido, got)
+		}
+	}
+}
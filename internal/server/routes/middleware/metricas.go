@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"go-gorilla-autos/internal/metricas"
+
+	"github.com/gorilla/mux"
+)
+
+// MetricasMiddleware cuenta las solicitudes y mide su duración por ruta. Las que no
+// coinciden con una ruta registrada se cuentan con la ruta "desconocida".
+func MetricasMiddleware(m *metricas.Metricas) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inicio := time.Now()
+			respuesta := &respuestaConEstado{ResponseWriter: w}
+			next.ServeHTTP(respuesta, r)
+
+			m.ObservarSolicitud(r.Method, plantillaRuta(r), respuesta.Status(), time.Since(inicio))
+		})
+	}
+}
+
+// plantillaRuta devuelve la plantilla de la ruta de mux, sin los valores de las variables
+func plantillaRuta(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if plantilla, err := route.GetPathTemplate(); err == nil {
+			return plantilla
+		}
+	}
+	return "desconocida"
+}
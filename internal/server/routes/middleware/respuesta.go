@@ -0,0 +1,34 @@
+package middleware
+
+import "net/http"
+
+// respuestaConEstado guarda el código de respuesta y la cantidad de bytes que escribe el handler
+type respuestaConEstado struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *respuestaConEstado) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *respuestaConEstado) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Status devuelve el código de respuesta; 200 si el handler no escribió nada
+func (w *respuestaConEstado) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
+)
+
+// UsuarioMiddleware rechaza las escrituras que no declaran quién las hace en el header
+// X-Usuario. El token de autorización es compartido, así que el historial de cambios
+// solo puede registrar al usuario que declara el panel.
+func UsuarioMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			if strings.TrimSpace(r.Header.Get(historial.HeaderUsuario)) == "" {
+				helpers.ErrorResponse(w, r, helpers.CodigoUsuarioRequerido, "Se requiere el header "+historial.HeaderUsuario+" con el usuario que hace el cambio")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUsuarioMiddleware(t *testing.T) {
+	handler := UsuarioMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	casos := []struct {
+		method  string
+		usuario string
+		status  int
+	}{
+		{"GET", "", http.StatusNoContent},
+		{"OPTIONS", "", http.StatusNoContent},
+		{"PUT", "ana", http.StatusNoContent},
+		{"POST", "", http.StatusBadRequest},
+		{"DELETE", "  ", http.StatusBadRequest},
+		{"PATCH", "", http.StatusBadRequest},
+	}
+	for _, caso := range casos {
+		req := httptest.NewRequest(caso.method, "/api/admin/autos/T0001", nil)
+		if caso.usuario != "" {
+			req.Header.Set("X-Usuario", caso.usuario)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != caso.status {
+			t.Errorf("%s con usuario %q: status = %d, se esperaba %d", caso.method, caso.usuario, rec.Code, caso.status)
+		}
+		if rec.Code == http.StatusBadRequest {
+			var respuesta struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			json.NewDecoder(rec.Body).Decode(&respuesta)
+			if respuesta.Error.Code != "usuario_requerido" {
+				t.Errorf("%s sin usuario: code = %q", caso.method, respuesta.Error.Code)
+			}
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package operaciones
+
+import (
+	"net/http"
+
+	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/metricas"
+	"go-gorilla-autos/internal/server/handlers/operaciones"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterOperacionesRoutes registra /healthz, /readyz, /metrics y /version en la raíz
+// del router, fuera de /api y sin autenticación, para usarlos como sondas y para Prometheus
+func RegisterOperacionesRoutes(r *mux.Router, db database.Service, m *metricas.Metricas) {
+	r.HandleFunc("/healthz", operaciones.HealthzHandler).Methods("GET", "HEAD")
+
+	r.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		operaciones.ReadyzHandler(w, r, db)
+	}).Methods("GET", "HEAD")
+
+	r.Handle("/metrics", m.Handler()).Methods("GET")
+
+	r.HandleFunc("/version", operaciones.VersionHandler).Methods("GET")
+}
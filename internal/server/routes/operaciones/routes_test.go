@@ -0,0 +1,68 @@
+package operaciones
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/metricas"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// baseFalsa implementa database.Service devolviendo el error indicado en Health
+type baseFalsa struct {
+	err error
+}
+
+func (b baseFalsa) Collection(name string) *mongo.Collection { return nil }
+func (b baseFalsa) Health(ctx context.Context) error         { return b.err }
+func (b baseFalsa) Close(ctx context.Context) error          { return nil }
+
+func pedir(t *testing.T, db baseFalsa, url string) (int, map[string]interface{}) {
+	t.Helper()
+	r := mux.NewRouter()
+	RegisterOperacionesRoutes(r, db, metricas.New(repository.NewMemoria()))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", url, nil))
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("GET %s: respuesta inválida: %v", url, err)
+	}
+	return rec.Code, body
+}
+
+func TestHealthz(t *testing.T) {
+	// /healthz no depende de la base
+	code, body := pedir(t, baseFalsa{err: errors.New("sin conexión")}, "/healthz")
+	if code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("status = %d, body = %v", code, body)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	code, body := pedir(t, baseFalsa{}, "/readyz")
+	mongo, _ := body["mongo"].(map[string]interface{})
+	if code != http.StatusOK || mongo["status"] != "ok" || mongo["latencia_ms"] == nil {
+		t.Errorf("status = %d, body = %v", code, body)
+	}
+
+	code, body = pedir(t, baseFalsa{err: errors.New("sin conexión")}, "/readyz")
+	mongo, _ = body["mongo"].(map[string]interface{})
+	if code != http.StatusServiceUnavailable || mongo["error"] != "sin conexión" {
+		t.Errorf("base caída: status = %d, body = %v", code, body)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	code, body := pedir(t, baseFalsa{}, "/version")
+	if code != http.StatusOK || body["go_version"] == "" || body["commit"] == "" || body["build_time"] == "" {
+		t.Errorf("status = %d, body = %v", code, body)
+	}
+}
@@ -3,60 +3,123 @@ package private
 import (
 	"net/http"
 
-	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/database/repository"
 	"go-gorilla-autos/internal/server/handlers/private"
+	"go-gorilla-autos/internal/server/handlers/private/campanias"
 	"go-gorilla-autos/internal/server/handlers/private/descuentos"
 	"go-gorilla-autos/internal/server/handlers/private/destacado"
 	"go-gorilla-autos/internal/server/handlers/private/estado"
+	"go-gorilla-autos/internal/server/handlers/private/historial"
+	"go-gorilla-autos/internal/server/handlers/private/monedas"
+	"go-gorilla-autos/internal/server/handlers/private/papelera"
+	"go-gorilla-autos/internal/server/handlers/private/precios"
 	"go-gorilla-autos/internal/server/handlers/private/reserva"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterPrivateRoutes(privateRouter *mux.Router, db database.Service) {
+func RegisterPrivateRoutes(privateRouter *mux.Router, autos repository.AutoRepository) {
 	privateRouter.HandleFunc("/autos", func(w http.ResponseWriter, r *http.Request) {
-		private.CreateAutoHandler(w, r, db)
+		private.GetAutosAdminHandler(w, r, autos)
+	}).Methods("GET")
+
+	privateRouter.HandleFunc("/autos", func(w http.ResponseWriter, r *http.Request) {
+		private.CreateAutoHandler(w, r, autos)
 	}).Methods("POST")
 
+	// La papelera va antes de /autos/{stock_id} para que "trash" no se tome como stock_id
+	privateRouter.HandleFunc("/autos/trash", func(w http.ResponseWriter, r *http.Request) {
+		papelera.ListarPapeleraHandler(w, r, autos)
+	}).Methods("GET")
+
+	privateRouter.HandleFunc("/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
+		private.GetAutoAdminHandler(w, r, autos)
+	}).Methods("GET")
+
 	privateRouter.HandleFunc("/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
-		private.UpdateAutoHandler(w, r, db)
+		private.UpdateAutoHandler(w, r, autos)
 	}).Methods("PUT")
 
 	privateRouter.HandleFunc("/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
-		private.DeleteAutoHandler(w, r, db)
+		private.PatchAutoHandler(w, r, autos)
+	}).Methods("PATCH")
+
+	privateRouter.HandleFunc("/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
+		private.DeleteAutoHandler(w, r, autos)
 	}).Methods("DELETE")
 
+	privateRouter.HandleFunc("/autos/{stock_id}/restore", func(w http.ResponseWriter, r *http.Request) {
+		papelera.RestaurarAutoHandler(w, r, autos)
+	}).Methods("POST")
+
+	privateRouter.HandleFunc("/autos/{stock_id}/history", func(w http.ResponseWriter, r *http.Request) {
+		historial.GetHistorialHandler(w, r, autos)
+	}).Methods("GET")
+
+	privateRouter.HandleFunc("/autos/{stock_id}/prices", func(w http.ResponseWriter, r *http.Request) {
+		precios.GetHistorialPreciosHandler(w, r, autos)
+	}).Methods("GET")
+
 	privateRouter.HandleFunc("/autos/{stock_id}/featured", func(w http.ResponseWriter, r *http.Request) {
-		destacado.ToggleFeaturedHandler(w, r, db)
+		destacado.ToggleFeaturedHandler(w, r, autos)
 	}).Methods("POST")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/status", func(w http.ResponseWriter, r *http.Request) {
-		estado.CambiarEstadoAutoHandler(w, r, db)
+		estado.CambiarEstadoAutoHandler(w, r, autos)
 	}).Methods("POST")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/discount", func(w http.ResponseWriter, r *http.Request) {
-		descuentos.AplicarDescuentoHandler(w, r, db)
+		descuentos.AplicarDescuentoHandler(w, r, autos)
 	}).Methods("POST")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/discount", func(w http.ResponseWriter, r *http.Request) {
-		descuentos.EliminarDescuentoHandler(w, r, db)
+		descuentos.EliminarDescuentoHandler(w, r, autos)
 	}).Methods("DELETE")
 
 	// Ruta para obtener reservas de un auto
 	privateRouter.HandleFunc("/autos/{stock_id}/reservations", func(w http.ResponseWriter, r *http.Request) {
-		reserva.ObtenerReservasHandler(w, r, db)
+		reserva.ObtenerReservasHandler(w, r, autos)
 	}).Methods("GET")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/reservations", func(w http.ResponseWriter, r *http.Request) {
-		reserva.CrearReservaHandler(w, r, db)
+		reserva.CrearReservaHandler(w, r, autos)
 	}).Methods("POST")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/reservations/{reserva_id}", func(w http.ResponseWriter, r *http.Request) {
-		reserva.EditarReservaHandler(w, r, db)
+		reserva.EditarReservaHandler(w, r, autos)
 	}).Methods("PUT")
 
 	privateRouter.HandleFunc("/autos/{stock_id}/reservations/{reserva_id}", func(w http.ResponseWriter, r *http.Request) {
-		reserva.EliminarReservaHandler(w, r, db)
+		reserva.EliminarReservaHandler(w, r, autos)
+	}).Methods("DELETE")
+
+	// Campañas de descuento sobre grupos de autos
+	privateRouter.HandleFunc("/campaigns", func(w http.ResponseWriter, r *http.Request) {
+		campanias.ListarCampaniasHandler(w, r, autos)
+	}).Methods("GET")
+
+	privateRouter.HandleFunc("/campaigns", func(w http.ResponseWriter, r *http.Request) {
+		campanias.CrearCampaniaHandler(w, r, autos)
+	}).Methods("POST")
+
+	privateRouter.HandleFunc("/campaigns/preview", func(w http.ResponseWriter, r *http.Request) {
+		campanias.PreviewCampaniaHandler(w, r, autos)
+	}).Methods("POST")
+
+	privateRouter.HandleFunc("/campaigns/{campania_id}", func(w http.ResponseWriter, r *http.Request) {
+		campanias.EliminarCampaniaHandler(w, r, autos)
 	}).Methods("DELETE")
 
+	// Tipos de cambio para normalizar precios entre monedas
+	privateRouter.HandleFunc("/exchange-rates", func(w http.ResponseWriter, r *http.Request) {
+		monedas.ListarTiposCambioHandler(w, r, autos)
+	}).Methods("GET")
+
+	privateRouter.HandleFunc("/exchange-rates/{moneda}", func(w http.ResponseWriter, r *http.Request) {
+		monedas.ActualizarTipoCambioHandler(w, r, autos)
+	}).Methods("PUT")
+
+	privateRouter.HandleFunc("/exchange-rates/{moneda}/history", func(w http.ResponseWriter, r *http.Request) {
+		monedas.GetHistorialTipoCambioHandler(w, r, autos)
+	}).Methods("GET")
 }
@@ -0,0 +1,865 @@
+package private
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// nuevoAdmin arma el router privado sobre un repositorio en memoria con un auto T0001
+func nuevoAdmin(t *testing.T) (*mux.Router, *repository.Memoria) {
+	t.Helper()
+	repo := repository.NewMemoria()
+
+	now := time.Now()
+	auto := models.Auto{
+		StockID:               "T0001",
+		Marca:                 "Toyota",
+		Modelo:                "Corolla",
+		Version:               "1.8 XEi",
+		TipoVenta:             "usado",
+		Año:                   2019,
+		Kilometraje:           40000,
+		Precio:                10000,
+		PrecioLista:           10000,
+		Ciudad:                "Córdoba",
+		Transmision:           "manual",
+		Traccion:              "4x2",
+		Sucursal:              "Centro",
+		Garantia:              "3 meses",
+		Estado:                models.EstadoDisponible,
+		TipoCombustible:       "nafta",
+		Moneda:                "USD",
+		EquipamientoDestacado: []string{"Aire acondicionado"},
+		CreatedAt:             now,
+		UpdatedAt:             now,
+	}
+	auto.IniciarHistorialPrecios(now)
+	if err := repo.Create(context.Background(), auto); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	r := mux.NewRouter()
+	RegisterPrivateRoutes(r, repo)
+	return r, repo
+}
+
+// pedir ejecuta la solicitud contra el router y decodifica la respuesta JSON en destino
+func pedir(t *testing.T, r http.Handler, method string, url string, body string, destino interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if destino != nil {
+		if err := json.NewDecoder(rec.Body).Decode(destino); err != nil {
+			t.Fatalf("%s %s: respuesta inválida: %v", method, url, err)
+		}
+	}
+	return rec.Code
+}
+
+// respuestaError es el formato común de las respuestas de error
+type respuestaError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Mensaje string `json:"mensaje"`
+		Details struct {
+			Campos []models.ErrorCampo `json:"campos"`
+		} `json:"details"`
+		RequestID string `json:"request_id"`
+	} `json:"error"`
+}
+
+const autoNuevo = `{
+	"marca": "Ford", "modelo": "Focus", "version": "2.0 SE", "tipo_venta": "usado",
+	"año": 2017, "kilometraje": 80000, "precio": 9000, "ciudad": "Córdoba",
+	"transmision": "manual", "traccion": "4x2", "sucursal": "Centro", "garantia": "3 meses",
+	"tipo_combustible": "nafta", "moneda": "USD", "equipamiento_destacado": ["ABS"]
+}`
+
+func TestCreateAuto(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	var respuesta struct {
+		Auto models.Auto `json:"auto"`
+	}
+	if code := pedir(t, r, "POST", "/autos", autoNuevo, &respuesta); code != http.StatusCreated {
+		t.Fatalf("status = %d, se esperaba 201", code)
+	}
+	stockID := respuesta.Auto.StockID
+	if !strings.HasPrefix(stockID, "F") {
+		t.Fatalf("stock_id = %q, se esperaba prefijo F", stockID)
+	}
+	guardado, err := repo.FindByStockID(context.Background(), stockID)
+	if err != nil || guardado.PrecioLista != 9000 || len(guardado.HistorialPrecios) != 1 {
+		t.Errorf("auto guardado = %+v, %v", guardado, err)
+	}
+
+	// El segundo auto de la misma marca toma el número siguiente
+	if pedir(t, r, "POST", "/autos", autoNuevo, &respuesta); respuesta.Auto.StockID == stockID {
+		t.Errorf("stock_id repetido %q", stockID)
+	}
+
+	var fallo respuestaError
+	if code := pedir(t, r, "POST", "/autos", `{"marca": "Ford"}`, &fallo); code != http.StatusUnprocessableEntity {
+		t.Errorf("auto incompleto: status = %d, se esperaba 422", code)
+	}
+	if fallo.Error.Code != "campos_invalidos" || len(fallo.Error.Details.Campos) == 0 {
+		t.Errorf("error = %+v", fallo.Error)
+	}
+}
+
+func TestCreateAutoIgnoraCamposDelServidor(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	body := strings.Replace(autoNuevo, `"moneda": "USD",`, `"moneda": "USD",
+	"deleted_at": "2024-01-01T00:00:00Z", "revision": 7,
+	"reservas": [{"id": "A123", "nombre": "Ana", "apellido": "Pérez", "telefono": "1155550000"}],
+	"reservado_por": {"nombre": "Ana"}, "en_mantenimiento": {"taller": "Sur"},
+	"historial_estados": [{"desde": "vendido", "hacia": "disponible"}],
+	"precio_anterior": 99999, "bajo_de_precio": true,`, 1)
+	var respuesta struct {
+		Auto models.Auto `json:"auto"`
+	}
+	if code := pedir(t, r, "POST", "/autos", body, &respuesta); code != http.StatusCreated {
+		t.Fatalf("status = %d, se esperaba 201", code)
+	}
+
+	guardado, err := repo.FindByStockID(context.Background(), respuesta.Auto.StockID)
+	if err != nil {
+		t.Fatalf("el auto creado no se encuentra: %v", err)
+	}
+	if guardado.DeletedAt != nil || guardado.Revision != 1 || len(guardado.Reservas) != 0 || guardado.ReservadoPor != nil ||
+		guardado.EnMantenimiento != nil || len(guardado.HistorialEstados) != 0 || guardado.PrecioAnterior != 0 || guardado.BajoDePrecio {
+		t.Errorf("auto guardado con campos del servidor = %+v", guardado)
+	}
+	var papelera struct {
+		Total int `json:"total"`
+	}
+	if pedir(t, r, "GET", "/autos/trash", "", &papelera); papelera.Total != 0 {
+		t.Errorf("papelera = %d autos, se esperaba 0", papelera.Total)
+	}
+}
+
+func TestCreateAutoCamposInvalidos(t *testing.T) {
+	r, _ := nuevoAdmin(t)
+
+	invalido := strings.NewReplacer(
+		`"año": 2017`, `"año": 1800`,
+		`"transmision": "manual"`, `"transmision": "a pedal"`,
+		`"moneda": "USD"`, `"moneda": "usd"`,
+		`"equipamiento_destacado": ["ABS"]`, `"equipamiento_destacado": ["ABS"], "imagenes": ["https://img.example.com/1.jpg", "foto.jpg"]`,
+	).Replace(autoNuevo)
+
+	var fallo respuestaError
+	if code := pedir(t, r, "POST", "/autos", invalido, &fallo); code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, se esperaba 422", code)
+	}
+	reglas := map[string]string{}
+	for _, campo := range fallo.Error.Details.Campos {
+		reglas[campo.Campo] = campo.Regla
+	}
+	esperadas := map[string]string{"año": "min", "transmision": "enum", "imagenes[1]": "format"}
+	if len(reglas) != len(esperadas) {
+		t.Errorf("campos = %+v, se esperaban %v", fallo.Error.Details.Campos, esperadas)
+	}
+	for campo, regla := range esperadas {
+		if reglas[campo] != regla {
+			t.Errorf("%s: regla = %q, se esperaba %q", campo, reglas[campo], regla)
+		}
+	}
+}
+
+func TestUpdateYDeleteAuto(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	actualizado := strings.Replace(autoNuevo, `"precio": 9000`, `"precio": 8000`, 1)
+	if code := pedir(t, r, "PUT", "/autos/T0001", actualizado, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	if auto.PrecioLista != 8000 || !auto.BajoDePrecio {
+		t.Errorf("auto actualizado = %+v", auto)
+	}
+	cambios, _ := repo.Historial(ctx, "T0001", "")
+	if len(cambios) == 0 {
+		t.Error("la actualización no quedó en el historial")
+	}
+
+	if code := pedir(t, r, "PUT", "/autos/T0099", actualizado, nil); code != http.StatusNotFound {
+		t.Errorf("auto inexistente: status = %d, se esperaba 404", code)
+	}
+
+	if code := pedir(t, r, "DELETE", "/autos/T0001", "", nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if _, err := repo.FindByStockID(ctx, "T0001"); err == nil {
+		t.Error("el auto sigue existiendo después de eliminarlo")
+	}
+	if code := pedir(t, r, "DELETE", "/autos/T0001", "", nil); code != http.StatusNotFound {
+		t.Errorf("eliminar dos veces: status = %d, se esperaba 404", code)
+	}
+}
+
+func TestGetAutosAdmin(t *testing.T) {
+	r, _ := nuevoAdmin(t)
+
+	var autos []models.Auto
+	if code := pedir(t, r, "GET", "/autos", "", &autos); code != http.StatusOK || len(autos) != 1 {
+		t.Errorf("status = %d, autos = %+v", code, autos)
+	}
+	var auto models.Auto
+	if code := pedir(t, r, "GET", "/autos/T0001", "", &auto); code != http.StatusOK || auto.Modelo != "Corolla" {
+		t.Errorf("status = %d, auto = %+v", code, auto)
+	}
+}
+
+func TestCambiarEstado(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	negociacion := `{"estado": "en negociación", "en_negociacion": {"nombre": "Ana", "apellido": "Pérez"}}`
+	if code := pedir(t, r, "POST", "/autos/T0001/status", negociacion, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(context.Background(), "T0001")
+	if auto.Estado != models.EstadoEnNegociacion || auto.EnNegociacion == nil {
+		t.Errorf("auto = %+v", auto)
+	}
+
+	if code := pedir(t, r, "POST", "/autos/T0001/status", `{"estado": "reservado"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("reservado sin reservado_por: status = %d, se esperaba 400", code)
+	}
+	if code := pedir(t, r, "POST", "/autos/T0099/status", `{"estado": "disponible"}`, nil); code != http.StatusNotFound {
+		t.Errorf("auto inexistente: status = %d, se esperaba 404", code)
+	}
+}
+
+func TestTransicionNoPermitida(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	vendido := `{"estado": "vendido", "vendido_por": {"nombre": "Ana", "apellido": "Pérez"}}`
+	var fallo respuestaError
+	if code := pedir(t, r, "POST", "/autos/T0001/status", vendido, &fallo); code != http.StatusConflict {
+		t.Errorf("disponible a vendido: status = %d, se esperaba 409", code)
+	}
+	if fallo.Error.Code != "transicion_no_permitida" || fallo.Error.Mensaje == "" {
+		t.Errorf("error = %+v", fallo.Error)
+	}
+
+	forzado := `{"estado": "vendido", "forzar": true, "vendido_por": {"nombre": "Ana", "apellido": "Pérez"}}`
+	if code := pedir(t, r, "POST", "/autos/T0001/status", forzado, nil); code != http.StatusOK {
+		t.Fatalf("transición forzada: status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(context.Background(), "T0001")
+	if auto.Estado != models.EstadoVendido || len(auto.HistorialEstados) != 1 || !auto.HistorialEstados[0].Forzada {
+		t.Errorf("auto vendido = %+v", auto)
+	}
+}
+
+func TestDescuento(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	var aplicado struct {
+		PrecioConDescuento float64 `json:"precio_con_descuento"`
+	}
+	if code := pedir(t, r, "POST", "/autos/T0001/discount", `{"tipo": "porcentaje", "valor": 10}`, &aplicado); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if aplicado.PrecioConDescuento != 9000 {
+		t.Errorf("precio con descuento = %v, se esperaba 9000", aplicado.PrecioConDescuento)
+	}
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	if auto.Promocion == nil || auto.PrecioLista != 10000 || !auto.BajoDePrecio {
+		t.Errorf("auto con descuento = %+v", auto)
+	}
+
+	if code := pedir(t, r, "POST", "/autos/T0001/discount", `{"tipo": "porcentaje", "valor": 5}`, nil); code != http.StatusBadRequest {
+		t.Errorf("segundo descuento: status = %d, se esperaba 400", code)
+	}
+
+	if code := pedir(t, r, "DELETE", "/autos/T0001/discount", "", nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ = repo.FindByStockID(ctx, "T0001")
+	if auto.Promocion != nil || auto.Precio != 10000 {
+		t.Errorf("auto sin descuento = %+v", auto)
+	}
+}
+
+func TestEliminarDescuentoConCampania(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+	campania := models.PromocionCampania{
+		Promocion:   models.Promocion{Tipo: models.DescuentoPorcentaje, Valor: 5, CreadoEn: time.Now()},
+		CampaniaID:  "c1",
+		Precedencia: models.PrecedenciaMejorPrecio,
+	}
+	if err := repo.AsignarCampania(ctx, []string{"T0001"}, campania, time.Now()); err != nil {
+		t.Fatalf("AsignarCampania: %v", err)
+	}
+	if code := pedir(t, r, "POST", "/autos/T0001/discount", `{"tipo": "porcentaje", "valor": 10}`, nil); code != http.StatusOK {
+		t.Fatalf("aplicar: status = %d, se esperaba 200", code)
+	}
+
+	// Al quitar el descuento individual sigue vigente el de la campaña
+	var eliminado struct {
+		Descuento float64 `json:"descuento"`
+		Precio    float64 `json:"precio"`
+	}
+	if code := pedir(t, r, "DELETE", "/autos/T0001/discount", "", &eliminado); code != http.StatusOK || eliminado.Descuento != 500 || eliminado.Precio != 9500 {
+		t.Fatalf("eliminar: status = %d, %+v", code, eliminado)
+	}
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	ultimo := auto.HistorialPrecios[len(auto.HistorialPrecios)-1]
+	if auto.Precio != 9500 || ultimo.Precio != 9500 || ultimo.Motivo != models.MotivoPrecioSinDescuento {
+		t.Errorf("precio = %v, último punto = %+v", auto.Precio, ultimo)
+	}
+}
+
+func TestHistorialPreciosVentanas(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+	// El repositorio guarda las fechas con precisión de milisegundos, como Mongo
+	now := time.Now().Truncate(time.Millisecond)
+	desde := now.Add(-48 * time.Hour)
+	hasta := now.Add(-24 * time.Hour)
+
+	// Un descuento programado que se abrió después del último punto guardado
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	auto.HistorialPrecios = []models.PrecioHistorico{{Precio: 10000, Fecha: now.Add(-72 * time.Hour), Motivo: models.MotivoPrecioAlta}}
+	auto.Promocion = &models.Promocion{Tipo: models.DescuentoPorcentaje, Valor: 10, ValidoDesde: &desde, CreadoEn: now.Add(-72 * time.Hour)}
+	if err := repo.UpdatePrecios(ctx, auto); err != nil {
+		t.Fatalf("UpdatePrecios: %v", err)
+	}
+
+	var precios struct {
+		PrecioActual float64                  `json:"precio_actual"`
+		BajoDePrecio bool                     `json:"bajo_de_precio"`
+		Historial    []models.PrecioHistorico `json:"historial"`
+	}
+	if code := pedir(t, r, "GET", "/autos/T0001/prices", "", &precios); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if len(precios.Historial) != 2 || precios.Historial[1].Precio != 9000 || precios.Historial[1].Motivo != models.MotivoPrecioInicioDescuento || !precios.Historial[1].Fecha.Equal(desde) {
+		t.Errorf("serie con ventana abierta = %+v", precios.Historial)
+	}
+	if precios.PrecioActual != 9000 || !precios.BajoDePrecio {
+		t.Errorf("precio actual = %v, bajo de precio = %v", precios.PrecioActual, precios.BajoDePrecio)
+	}
+
+	// Al cerrarse la ventana el gráfico vuelve al precio de lista, igual que el indicador
+	auto, _ = repo.FindByStockID(ctx, "T0001")
+	auto.Promocion.ValidoHasta = &hasta
+	if err := repo.UpdatePrecios(ctx, auto); err != nil {
+		t.Fatalf("UpdatePrecios: %v", err)
+	}
+	precios.Historial = nil
+	if code := pedir(t, r, "GET", "/autos/T0001/prices", "", &precios); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if len(precios.Historial) != 3 || precios.Historial[2].Precio != 10000 || precios.Historial[2].Motivo != models.MotivoPrecioFinDescuento {
+		t.Errorf("serie con ventana cerrada = %+v", precios.Historial)
+	}
+	if precios.PrecioActual != 10000 || precios.BajoDePrecio {
+		t.Errorf("precio actual = %v, bajo de precio = %v", precios.PrecioActual, precios.BajoDePrecio)
+	}
+
+	// Los puntos agregados no se guardan
+	auto, _ = repo.FindByStockID(ctx, "T0001")
+	if len(auto.HistorialPrecios) != 1 {
+		t.Errorf("serie guardada = %+v", auto.HistorialPrecios)
+	}
+}
+
+func TestCampanias(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+	hasta := time.Now().AddDate(0, 0, 7).UTC().Format(time.RFC3339)
+	campania := `{"nombre": "Semana Toyota", "filtro": {"marca": "toyota"}, "promocion": {"tipo": "porcentaje", "valor": 10, "valido_hasta": "` + hasta + `"}}`
+
+	var preview struct {
+		Total      int `json:"total"`
+		Aplicables int `json:"aplicables"`
+		Autos      []struct {
+			StockID           string  `json:"stock_id"`
+			PrecioConCampania float64 `json:"precio_con_campania"`
+		} `json:"autos"`
+	}
+	if code := pedir(t, r, "POST", "/campaigns/preview", campania, &preview); code != http.StatusOK || preview.Aplicables != 1 || preview.Autos[0].PrecioConCampania != 9000 {
+		t.Fatalf("preview: status = %d, %+v", code, preview)
+	}
+	if auto, _ := repo.FindByStockID(ctx, "T0001"); auto.Campania != nil {
+		t.Errorf("la vista previa asignó la campaña: %+v", auto.Campania)
+	}
+
+	var creada struct {
+		Campania models.Campania `json:"campania"`
+		Total    int             `json:"total"`
+	}
+	if code := pedir(t, r, "POST", "/campaigns", campania, &creada); code != http.StatusCreated || creada.Total != 1 || creada.Campania.ID == "" {
+		t.Fatalf("crear: status = %d, %+v", code, creada)
+	}
+	var auto models.Auto
+	if pedir(t, r, "GET", "/autos/T0001", "", &auto); auto.Campania == nil || auto.Campania.CampaniaID != creada.Campania.ID || auto.Precio != 9000 {
+		t.Errorf("auto con campaña: precio = %v, campaña = %+v", auto.Precio, auto.Campania)
+	}
+
+	var campanias []struct {
+		ID     string `json:"id"`
+		Activa bool   `json:"activa"`
+	}
+	if code := pedir(t, r, "GET", "/campaigns", "", &campanias); code != http.StatusOK || len(campanias) != 1 || campanias[0].ID != creada.Campania.ID || !campanias[0].Activa {
+		t.Fatalf("listar: status = %d, %+v", code, campanias)
+	}
+
+	var eliminada struct {
+		Autos int64 `json:"autos"`
+	}
+	if code := pedir(t, r, "DELETE", "/campaigns/"+creada.Campania.ID, "", &eliminada); code != http.StatusOK || eliminada.Autos != 1 {
+		t.Fatalf("eliminar: status = %d, %+v", code, eliminada)
+	}
+	if auto, _ := repo.FindByStockID(ctx, "T0001"); auto.Campania != nil {
+		t.Errorf("campaña después de eliminarla = %+v", auto.Campania)
+	}
+	var respuesta respuestaError
+	if code := pedir(t, r, "DELETE", "/campaigns/"+creada.Campania.ID, "", &respuesta); code != http.StatusNotFound || respuesta.Error.Code != "campania_no_encontrada" {
+		t.Errorf("eliminar dos veces: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+	if pedir(t, r, "GET", "/campaigns", "", &campanias); len(campanias) != 0 {
+		t.Errorf("campañas después de eliminar = %+v", campanias)
+	}
+}
+
+func TestCampaniaPorMontoConMonedasDistintas(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+	ars, _ := repo.FindByStockID(ctx, "T0001")
+	ars.StockID = "T0002"
+	ars.Moneda = "ARS"
+	ars.Precio, ars.PrecioLista = 20000000, 20000000
+	if err := repo.Create(ctx, ars); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// Los autos solo se pueden editar en monedas con tipo de cambio cargado
+	if err := repo.GuardarTipoCambio(ctx, models.TipoCambio{Moneda: "ARS", Tasa: 1000, ActualizadoEn: time.Now()}); err != nil {
+		t.Fatalf("GuardarTipoCambio: %v", err)
+	}
+	hasta := time.Now().AddDate(0, 0, 7).UTC().Format(time.RFC3339)
+	campania := func(moneda string) string {
+		return `{"nombre": "Toyota", "filtro": {"marca": "toyota"}, "promocion": {"tipo": "monto", "valor": 500, "moneda": "` + moneda + `", "valido_hasta": "` + hasta + `"}}`
+	}
+
+	var respuesta respuestaError
+	if code := pedir(t, r, "POST", "/campaigns", campania(""), &respuesta); code != http.StatusBadRequest || respuesta.Error.Code != "validacion" {
+		t.Errorf("campaña por monto sin moneda: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+
+	// Solo alcanza a los autos en la moneda del monto
+	var creada struct {
+		Campania models.Campania `json:"campania"`
+		Total    int             `json:"total"`
+	}
+	if code := pedir(t, r, "POST", "/campaigns", campania("usd"), &creada); code != http.StatusCreated || creada.Total != 1 || creada.Campania.Promocion.Moneda != "USD" {
+		t.Fatalf("crear: status = %d, %+v", code, creada)
+	}
+	var usd, pesos models.Auto
+	pedir(t, r, "GET", "/autos/T0001", "", &usd)
+	pedir(t, r, "GET", "/autos/T0002", "", &pesos)
+	if usd.Precio != 9500 || usd.Campania == nil {
+		t.Errorf("auto en USD: precio = %v, campaña = %+v", usd.Precio, usd.Campania)
+	}
+	if pesos.Precio != 20000000 || pesos.Campania != nil {
+		t.Errorf("auto en ARS: precio = %v, campaña = %+v", pesos.Precio, pesos.Campania)
+	}
+
+	var preview struct {
+		Aplicables int `json:"aplicables"`
+		Autos      []struct {
+			StockID   string `json:"stock_id"`
+			Aplicable bool   `json:"aplicable"`
+			Motivo    string `json:"motivo"`
+		} `json:"autos"`
+	}
+	pedir(t, r, "POST", "/campaigns/preview", campania("ARS"), &preview)
+	if preview.Aplicables != 1 || preview.Autos[0].Aplicable || preview.Autos[0].Motivo == "" || !preview.Autos[1].Aplicable {
+		t.Errorf("preview en ARS = %+v", preview)
+	}
+
+	// El auto no puede pasar a otra moneda mientras tenga una campaña por monto
+	cambioMoneda := strings.Replace(autoNuevo, `"moneda": "USD"`, `"moneda": "ARS"`, 1)
+	if code := pedir(t, r, "PUT", "/autos/T0001", cambioMoneda, &respuesta); code != http.StatusBadRequest || respuesta.Error.Code != "descuento_invalido" {
+		t.Errorf("PUT a otra moneda: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+
+	// Tampoco se puede crear un auto con una promoción por monto en otra moneda
+	conPromocion := strings.Replace(autoNuevo, `"moneda": "USD",`, `"moneda": "ARS", "promocion": {"tipo": "monto", "valor": 500, "moneda": "USD"},`, 1)
+	if code := pedir(t, r, "POST", "/autos", conPromocion, &respuesta); code != http.StatusBadRequest || respuesta.Error.Code != "descuento_invalido" {
+		t.Errorf("POST con promoción en otra moneda: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+
+	// Un descuento individual por monto se toma en la moneda del auto
+	if code := pedir(t, r, "POST", "/autos/T0002/discount", `{"tipo": "monto", "valor": 500, "moneda": "USD"}`, &respuesta); code != http.StatusBadRequest || respuesta.Error.Code != "descuento_invalido" {
+		t.Errorf("descuento en otra moneda: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+	if code := pedir(t, r, "POST", "/autos/T0002/discount", `{"tipo": "monto", "valor": 500000}`, nil); code != http.StatusOK {
+		t.Fatalf("descuento sin moneda: status = %d, se esperaba 200", code)
+	}
+	if auto, _ := repo.FindByStockID(ctx, "T0002"); auto.Promocion == nil || auto.Promocion.Moneda != "ARS" {
+		t.Errorf("promoción = %+v, se esperaba moneda ARS", auto.Promocion)
+	}
+}
+
+func TestTiposCambio(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	auto, _ := repo.FindByStockID(context.Background(), "T0001")
+	auto.StockID = "T0002"
+	auto.Moneda = "ARS"
+	auto.Precio, auto.PrecioLista = 20000000, 20000000
+	if err := repo.Create(context.Background(), auto); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	type tabla struct {
+		MonedaReferencia string              `json:"moneda_referencia"`
+		TiposCambio      []models.TipoCambio `json:"tipos_cambio"`
+		SinTipoCambio    []string            `json:"sin_tipo_cambio"`
+	}
+	var vacia tabla
+	if code := pedir(t, r, "GET", "/exchange-rates", "", &vacia); code != http.StatusOK || vacia.MonedaReferencia != "USD" || len(vacia.TiposCambio) != 0 || len(vacia.SinTipoCambio) != 1 || vacia.SinTipoCambio[0] != "ARS" {
+		t.Fatalf("tabla vacía: status = %d, %+v", code, vacia)
+	}
+
+	// Sin tipo de cambio no se pueden cargar autos en esa moneda
+	enPesos := strings.Replace(autoNuevo, `"moneda": "USD"`, `"moneda": "ARS"`, 1)
+	var invalido respuestaError
+	if code := pedir(t, r, "POST", "/autos", enPesos, &invalido); code != http.StatusUnprocessableEntity || len(invalido.Error.Details.Campos) != 1 || invalido.Error.Details.Campos[0].Campo != "moneda" {
+		t.Errorf("auto en ARS sin tipo de cambio: status = %d, %+v", code, invalido.Error)
+	}
+
+	if code := pedir(t, r, "PUT", "/exchange-rates/ars", `{"tasa": 900}`, nil); code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, se esperaba 200", code)
+	}
+	if code := pedir(t, r, "PUT", "/exchange-rates/ARS", `{"tasa": 1000}`, nil); code != http.StatusOK {
+		t.Fatalf("PUT: status = %d, se esperaba 200", code)
+	}
+	var respuesta respuestaError
+	if code := pedir(t, r, "PUT", "/exchange-rates/USD", `{"tasa": 1}`, &respuesta); code != http.StatusBadRequest || respuesta.Error.Code != "validacion" {
+		t.Errorf("PUT de la moneda de referencia: status = %d, code = %q", code, respuesta.Error.Code)
+	}
+
+	var cargada tabla
+	if pedir(t, r, "GET", "/exchange-rates", "", &cargada); len(cargada.TiposCambio) != 1 || cargada.TiposCambio[0].Tasa != 1000 || len(cargada.SinTipoCambio) != 0 {
+		t.Errorf("tabla cargada = %+v", cargada)
+	}
+	if tasas, _ := repo.TiposCambio(context.Background()); tasas["ARS"] != 1000 {
+		t.Errorf("tasas = %v, se esperaba ARS 1000", tasas)
+	}
+	if code := pedir(t, r, "POST", "/autos", enPesos, nil); code != http.StatusCreated {
+		t.Errorf("auto en ARS con tipo de cambio: status = %d, se esperaba 201", code)
+	}
+
+	var historial struct {
+		Moneda    string              `json:"moneda"`
+		Historial []models.TipoCambio `json:"historial"`
+	}
+	if code := pedir(t, r, "GET", "/exchange-rates/ars/history", "", &historial); code != http.StatusOK || historial.Moneda != "ARS" || len(historial.Historial) != 2 || historial.Historial[0].Tasa != 1000 {
+		t.Errorf("historial: status = %d, %+v", code, historial)
+	}
+}
+
+func TestFeaturedYReservas(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	if code := pedir(t, r, "POST", "/autos/T0001/featured", `{"featured": true}`, nil); code != http.StatusOK {
+		t.Fatalf("featured: status = %d, se esperaba 200", code)
+	}
+	if auto, _ := repo.FindByStockID(ctx, "T0001"); !auto.Featured {
+		t.Error("el auto no quedó destacado")
+	}
+
+	reserva := `{"nombre": "Ana", "apellido": "Pérez", "fecha_hora": "2030-01-02T10:00:00Z"}`
+	if code := pedir(t, r, "POST", "/autos/T0001/reservations", reserva, nil); code != http.StatusCreated {
+		t.Fatalf("reserva: status = %d, se esperaba 201", code)
+	}
+	var reservas struct {
+		Reservas []models.Reserva `json:"reservas"`
+	}
+	if code := pedir(t, r, "GET", "/autos/T0001/reservations", "", &reservas); code != http.StatusOK || len(reservas.Reservas) != 1 {
+		t.Fatalf("status = %d, reservas = %+v", code, reservas)
+	}
+	id := reservas.Reservas[0].ID
+	if code := pedir(t, r, "POST", "/autos/T0001/reservations", reserva, nil); code != http.StatusConflict {
+		t.Errorf("reserva repetida: status = %d, se esperaba 409", code)
+	}
+
+	editada := `{"nombre": "Ana", "apellido": "Pérez", "telefono": "1155550000", "fecha_hora": "2030-01-03T10:00:00Z"}`
+	if code := pedir(t, r, "PUT", "/autos/T0001/reservations/"+id, editada, nil); code != http.StatusOK {
+		t.Fatalf("editar reserva: status = %d, se esperaba 200", code)
+	}
+	if auto, _ := repo.FindByStockID(ctx, "T0001"); len(auto.Reservas) != 1 || auto.Reservas[0].ID != id || auto.Reservas[0].Telefono != "1155550000" {
+		t.Errorf("reservas editadas = %+v", auto.Reservas)
+	}
+	if code := pedir(t, r, "PUT", "/autos/T0001/reservations/X000000", editada, nil); code != http.StatusNotFound {
+		t.Errorf("editar reserva inexistente: status = %d, se esperaba 404", code)
+	}
+
+	if code := pedir(t, r, "DELETE", "/autos/T0001/reservations/"+id, "", nil); code != http.StatusOK {
+		t.Errorf("eliminar reserva: status = %d, se esperaba 200", code)
+	}
+	if code := pedir(t, r, "DELETE", "/autos/T0001/reservations/"+id, "", nil); code != http.StatusNotFound {
+		t.Errorf("eliminar reserva dos veces: status = %d, se esperaba 404", code)
+	}
+}
+
+// parchear envía un PATCH con el tipo de contenido indicado y decodifica la respuesta JSON en destino
+func parchear(t *testing.T, r http.Handler, url string, tipo string, body string, destino interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest("PATCH", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", tipo)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if destino != nil {
+		if err := json.NewDecoder(rec.Body).Decode(destino); err != nil {
+			t.Fatalf("PATCH %s: respuesta inválida: %v", url, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestPatchAutoMerge(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	reserva := `{"nombre": "Ana", "apellido": "Pérez", "fecha_hora": "2030-01-02T10:00:00Z"}`
+	pedir(t, r, "POST", "/autos/T0001/reservations", reserva, nil)
+	pedir(t, r, "POST", "/autos/T0001/featured", `{"featured": true}`, nil)
+	antes, _ := repo.FindByStockID(ctx, "T0001")
+
+	parche := `{"precio": 9500, "kilometraje": 41000, "caracteristicas_general": {"color": "gris"}}`
+	if code := parchear(t, r, "/autos/T0001", "application/merge-patch+json", parche, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	if auto.PrecioLista != 9500 || auto.Kilometraje != 41000 || auto.CaracteristicasGeneral["color"] != "gris" {
+		t.Errorf("auto actualizado = %+v", auto)
+	}
+	if !auto.Featured || len(auto.Reservas) != 1 || !auto.CreatedAt.Equal(antes.CreatedAt) || auto.Modelo != "Corolla" {
+		t.Errorf("el parche borró campos que no envió: %+v", auto)
+	}
+
+	// null borra el miembro
+	parchear(t, r, "/autos/T0001", "application/merge-patch+json", `{"caracteristicas_general": null}`, nil)
+	if auto, _ := repo.FindByStockID(ctx, "T0001"); auto.CaracteristicasGeneral != nil {
+		t.Errorf("caracteristicas_general = %v, se esperaba nil", auto.CaracteristicasGeneral)
+	}
+}
+
+func TestPatchAutoJSONPatch(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	parche := `[
+		{"op": "test", "path": "/modelo", "value": "Corolla"},
+		{"op": "add", "path": "/equipamiento_destacado/-", "value": "ABS"},
+		{"op": "replace", "path": "/version", "value": "2.0 SEG"}
+	]`
+	if code := parchear(t, r, "/autos/T0001", "application/json-patch+json", parche, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(context.Background(), "T0001")
+	if auto.Version != "2.0 SEG" || len(auto.EquipamientoDestacado) != 2 || auto.PrecioLista != 10000 {
+		t.Errorf("auto actualizado = %+v", auto)
+	}
+
+	fallido := `[{"op": "test", "path": "/modelo", "value": "Etios"}, {"op": "replace", "path": "/version", "value": "1.5"}]`
+	if code := parchear(t, r, "/autos/T0001", "application/json-patch+json", fallido, nil); code != http.StatusConflict {
+		t.Errorf("test fallido: status = %d, se esperaba 409", code)
+	}
+	if code := parchear(t, r, "/autos/T0001", "application/json-patch+json", `{"op": "add"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("parche mal formado: status = %d, se esperaba 400", code)
+	}
+}
+
+func TestPatchAutoRechazos(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+
+	var fallo respuestaError
+	protegidos := `[{"op": "replace", "path": "/estado", "value": "vendido"}, {"op": "remove", "path": "/reservas"}]`
+	if code := parchear(t, r, "/autos/T0001", "application/json-patch+json", protegidos, &fallo); code != http.StatusUnprocessableEntity {
+		t.Fatalf("campos protegidos: status = %d, se esperaba 422", code)
+	}
+	if campos := fallo.Error.Details.Campos; len(campos) != 2 || campos[0].Campo != "estado" || campos[0].Regla != models.ReglaSoloLectura {
+		t.Errorf("campos = %+v", campos)
+	}
+
+	if code := parchear(t, r, "/autos/T0001", "application/merge-patch+json", `{"stock_id": "T0002"}`, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("stock_id: status = %d, se esperaba 422", code)
+	}
+	if code := parchear(t, r, "/autos/T0001", "application/merge-patch+json", `{"año": 1800}`, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("resultado inválido: status = %d, se esperaba 422", code)
+	}
+	if code := parchear(t, r, "/autos/T0001", "text/plain", `{"año": 2020}`, nil); code != http.StatusUnsupportedMediaType {
+		t.Errorf("tipo de contenido: status = %d, se esperaba 415", code)
+	}
+	if code := parchear(t, r, "/autos/T0099", "application/merge-patch+json", `{"año": 2020}`, nil); code != http.StatusNotFound {
+		t.Errorf("auto inexistente: status = %d, se esperaba 404", code)
+	}
+
+	if auto, _ := repo.FindByStockID(context.Background(), "T0001"); auto.Año != 2019 || auto.Estado != models.EstadoDisponible {
+		t.Errorf("un parche rechazado modificó el auto: %+v", auto)
+	}
+}
+
+func TestUpdateConservaCamposDelServidor(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	reserva := `{"nombre": "Ana", "apellido": "Pérez", "fecha_hora": "2030-01-02T10:00:00Z"}`
+	pedir(t, r, "POST", "/autos/T0001/reservations", reserva, nil)
+	antes, _ := repo.FindByStockID(ctx, "T0001")
+
+	if code := pedir(t, r, "PUT", "/autos/T0001", autoNuevo, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	if len(auto.Reservas) != 1 || !auto.CreatedAt.Equal(antes.CreatedAt) {
+		t.Errorf("PUT borró reservas o created_at: %+v", auto)
+	}
+}
+
+// pedirConIfMatch ejecuta la solicitud con el encabezado If-Match indicado
+func pedirConIfMatch(t *testing.T, r http.Handler, method string, url string, ifMatch string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("If-Match", ifMatch)
+	if method == "PATCH" {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIfMatch(t *testing.T) {
+	r, _ := nuevoAdmin(t)
+
+	rec := pedirConIfMatch(t, r, "GET", "/autos/T0001", "*", "")
+	if etag := rec.Header().Get("ETag"); rec.Code != http.StatusOK || etag != `"1"` {
+		t.Fatalf("GET: status = %d, ETag = %q, se esperaba 200 y \"1\"", rec.Code, etag)
+	}
+
+	// El primer PUT con la revisión leída gana y cambia la revisión
+	rec = pedirConIfMatch(t, r, "PUT", "/autos/T0001", `"1"`, autoNuevo)
+	if etag := rec.Header().Get("ETag"); rec.Code != http.StatusOK || etag != `"2"` {
+		t.Fatalf("PUT: status = %d, ETag = %q, se esperaba 200 y \"2\"", rec.Code, etag)
+	}
+
+	// Las escrituras con la revisión vieja responden 412 e informan la actual
+	viejas := []struct{ method, url, body string }{
+		{"PUT", "/autos/T0001", autoNuevo},
+		{"PATCH", "/autos/T0001", `{"kilometraje": 1}`},
+		{"POST", "/autos/T0001/status", `{"estado": "en negociación", "en_negociacion": {"nombre": "Ana"}}`},
+		{"POST", "/autos/T0001/discount", `{"tipo": "porcentaje", "valor": 10}`},
+		{"DELETE", "/autos/T0001/discount", ""},
+		{"DELETE", "/autos/T0001", ""},
+	}
+	for _, vieja := range viejas {
+		rec := pedirConIfMatch(t, r, vieja.method, vieja.url, `"1"`, vieja.body)
+		var respuesta respuestaError
+		json.NewDecoder(rec.Body).Decode(&respuesta)
+		if rec.Code != http.StatusPreconditionFailed || respuesta.Error.Code != "precondicion_fallida" || rec.Header().Get("ETag") != `"2"` {
+			t.Errorf("%s %s con revisión vieja: status = %d, code = %q, ETag = %q", vieja.method, vieja.url, rec.Code, respuesta.Error.Code, rec.Header().Get("ETag"))
+		}
+	}
+
+	// Las etiquetas débiles no cumplen If-Match; una lista con la actual sí
+	if rec := pedirConIfMatch(t, r, "PATCH", "/autos/T0001", `W/"2"`, `{"kilometraje": 1}`); rec.Code != http.StatusPreconditionFailed {
+		t.Errorf("PATCH con etiqueta débil: status = %d, se esperaba 412", rec.Code)
+	}
+	if rec := pedirConIfMatch(t, r, "PATCH", "/autos/T0001", `"1", "2"`, `{"kilometraje": 1}`); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"3"` {
+		t.Errorf("PATCH con la revisión actual: status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := pedirConIfMatch(t, r, "POST", "/autos/T0001/discount", `"3"`, `{"tipo": "porcentaje", "valor": 10}`); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"4"` {
+		t.Errorf("descuento con la revisión actual: status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := pedirConIfMatch(t, r, "DELETE", "/autos/T0001/discount", `"4"`, ""); rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"5"` {
+		t.Errorf("quitar descuento con la revisión actual: status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if rec := pedirConIfMatch(t, r, "DELETE", "/autos/T0001", `"5"`, ""); rec.Code != http.StatusOK {
+		t.Errorf("DELETE con la revisión actual: status = %d, se esperaba 200", rec.Code)
+	}
+}
+
+func TestPapelera(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	reserva := `{"nombre": "Ana", "apellido": "Pérez", "fecha_hora": "2030-01-02T10:00:00Z"}`
+	pedir(t, r, "POST", "/autos/T0001/reservations", reserva, nil)
+	if code := pedir(t, r, "DELETE", "/autos/T0001", "", nil); code != http.StatusOK {
+		t.Fatalf("DELETE: status = %d, se esperaba 200", code)
+	}
+
+	// El auto eliminado solo aparece en la papelera
+	if code := pedir(t, r, "GET", "/autos/T0001", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET eliminado: status = %d, se esperaba 404", code)
+	}
+	var lista []models.Auto
+	if pedir(t, r, "GET", "/autos", "", &lista); len(lista) != 0 {
+		t.Errorf("GET /autos = %d autos, se esperaba 0", len(lista))
+	}
+	var papelera struct {
+		Autos []models.Auto `json:"autos"`
+		Total int           `json:"total"`
+	}
+	if code := pedir(t, r, "GET", "/autos/trash", "", &papelera); code != http.StatusOK || papelera.Total != 1 || papelera.Autos[0].DeletedAt == nil {
+		t.Fatalf("papelera: status = %d, %+v", code, papelera)
+	}
+
+	// Al restaurar vuelve con sus reservas y una revisión nueva
+	req := httptest.NewRequest("POST", "/autos/T0001/restore", nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") != `"4"` {
+		t.Fatalf("restore: status = %d, ETag = %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	auto, err := repo.FindByStockID(ctx, "T0001")
+	if err != nil || len(auto.Reservas) != 1 || auto.Revision != 4 {
+		t.Errorf("auto restaurado = %+v, err = %v", auto, err)
+	}
+	if code := pedir(t, r, "POST", "/autos/T0001/restore", "", nil); code != http.StatusNotFound {
+		t.Errorf("restaurar dos veces: status = %d, se esperaba 404", code)
+	}
+
+	cambios, _ := repo.Historial(ctx, "T0001", "deleted_at")
+	if len(cambios) != 2 {
+		t.Errorf("historial de deleted_at = %d entradas, se esperaban 2", len(cambios))
+	}
+}
+
+func TestUpdateNoEnviaALaPapelera(t *testing.T) {
+	r, repo := nuevoAdmin(t)
+	ctx := context.Background()
+
+	conBorrado := strings.Replace(autoNuevo, `"precio": 9000`, `"precio": 9000, "deleted_at": "2000-01-01T00:00:00Z"`, 1)
+	if code := pedir(t, r, "PUT", "/autos/T0001", conBorrado, nil); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if _, err := repo.FindByStockID(ctx, "T0001"); err != nil {
+		t.Errorf("el PUT con deleted_at envió el auto a la papelera: %v", err)
+	}
+	if papelera, _ := repo.Papelera(ctx); len(papelera) != 0 {
+		t.Errorf("papelera = %d autos, se esperaba 0", len(papelera))
+	}
+}
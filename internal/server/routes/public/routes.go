@@ -3,24 +3,33 @@ package public
 import (
 	"net/http"
 
-	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/database/repository"
 	"go-gorilla-autos/internal/server/handlers/public"
 	"go-gorilla-autos/internal/server/handlers/public/reserva"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterPublicRoutes(r *mux.Router, db database.Service) {
+func RegisterPublicRoutes(r *mux.Router, autos repository.AutoRepository) {
 	publicRouter := r.PathPrefix("/api").Subrouter()
 	publicRouter.HandleFunc("/autos", func(w http.ResponseWriter, r *http.Request) {
-		public.GetAutosHandler(w, r, db)
+		public.GetAutosHandler(w, r, autos)
 	}).Methods("GET")
 
 	publicRouter.HandleFunc("/autos/destacados", func(w http.ResponseWriter, r *http.Request) {
-		public.GetFeaturedAutosHandler(w, r, db)
+		public.GetFeaturedAutosHandler(w, r, autos)
+	}).Methods("GET")
+
+	publicRouter.HandleFunc("/autos/facetas", func(w http.ResponseWriter, r *http.Request) {
+		public.GetFacetasHandler(w, r, autos)
+	}).Methods("GET")
+
+	// Debe registrarse después de /autos/destacados y /autos/facetas para no capturar esas rutas
+	publicRouter.HandleFunc("/autos/{stock_id}", func(w http.ResponseWriter, r *http.Request) {
+		public.GetAutoHandler(w, r, autos)
 	}).Methods("GET")
 
 	publicRouter.HandleFunc("/autos/{stock_id}/reservations", func(w http.ResponseWriter, r *http.Request) {
-		reserva.CrearReservaHandler(w, r, db)
+		reserva.CrearReservaHandler(w, r, autos)
 	}).Methods("POST")
 }
@@ -0,0 +1,343 @@
+package public
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-gorilla-autos/internal/database/models"
+	"go-gorilla-autos/internal/database/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// nuevoCatalogo arma el router público sobre un repositorio en memoria con tres autos
+func nuevoCatalogo(t *testing.T) (*mux.Router, *repository.Memoria) {
+	t.Helper()
+	repo := repository.NewMemoria()
+	for _, auto := range []models.Auto{
+		autoPrueba("T0001", "Toyota", 20000, true),
+		autoPrueba("T0002", "Toyota", 10000, false),
+		autoPrueba("F0001", "Ford", 15000, false),
+	} {
+		if err := repo.Create(context.Background(), auto); err != nil {
+			t.Fatalf("Create(%s): %v", auto.StockID, err)
+		}
+	}
+
+	r := mux.NewRouter()
+	RegisterPublicRoutes(r, repo)
+	return r, repo
+}
+
+func autoPrueba(stockID string, marca string, precio float64, destacado bool) models.Auto {
+	now := time.Now()
+	return models.Auto{
+		StockID:               stockID,
+		Marca:                 marca,
+		Modelo:                "Modelo",
+		Version:               "1.6",
+		TipoVenta:             "usado",
+		Año:                   2019,
+		Precio:                precio,
+		PrecioLista:           precio,
+		Ciudad:                "Córdoba",
+		Transmision:           "manual",
+		Traccion:              "4x2",
+		Sucursal:              "Centro",
+		Garantia:              "3 meses",
+		Featured:              destacado,
+		Estado:                models.EstadoDisponible,
+		TipoCombustible:       "nafta",
+		Moneda:                "USD",
+		EquipamientoDestacado: []string{"Aire acondicionado"},
+		CreatedAt:             now,
+		UpdatedAt:             now,
+	}
+}
+
+// pedir ejecuta la solicitud contra el router y decodifica la respuesta JSON en destino
+func pedir(t *testing.T, r http.Handler, method string, url string, body string, destino interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if destino != nil {
+		if err := json.NewDecoder(rec.Body).Decode(destino); err != nil {
+			t.Fatalf("%s %s: respuesta inválida: %v", method, url, err)
+		}
+	}
+	return rec.Code
+}
+
+type paginaPrueba struct {
+	Items []struct {
+		StockID string  `json:"stock_id"`
+		Precio  float64 `json:"precio"`
+	} `json:"items"`
+	Total      int64  `json:"total"`
+	Pages      int    `json:"pages"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func TestGetAutos(t *testing.T) {
+	r, _ := nuevoCatalogo(t)
+
+	var pagina paginaPrueba
+	if code := pedir(t, r, "GET", "/api/autos?marca=toyota&sort_precio=asc&per_page=1", "", &pagina); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if pagina.Total != 2 || pagina.Pages != 2 || len(pagina.Items) != 1 || pagina.Items[0].StockID != "T0002" {
+		t.Errorf("página = %+v", pagina)
+	}
+
+	if code := pedir(t, r, "GET", "/api/autos?sort=otro", "", nil); code != http.StatusBadRequest {
+		t.Errorf("orden inválido: status = %d, se esperaba 400", code)
+	}
+}
+
+func TestAutosEnPapeleraOcultos(t *testing.T) {
+	r, repo := nuevoCatalogo(t)
+	if err := repo.Delete(context.Background(), "T0002", 1, time.Now()); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	var pagina paginaPrueba
+	if code := pedir(t, r, "GET", "/api/autos?marca=toyota", "", &pagina); code != http.StatusOK || pagina.Total != 1 || pagina.Items[0].StockID != "T0001" {
+		t.Errorf("status = %d, página = %+v", code, pagina)
+	}
+	if code := pedir(t, r, "GET", "/api/autos/T0002", "", nil); code != http.StatusNotFound {
+		t.Errorf("detalle en la papelera: status = %d, se esperaba 404", code)
+	}
+	body := `{"nombre": "Ana", "apellido": "Pérez", "fecha_hora": "2030-01-02T10:00:00Z"}`
+	if code := pedir(t, r, "POST", "/api/autos/T0002/reservations", body, nil); code != http.StatusNotFound {
+		t.Errorf("reserva en la papelera: status = %d, se esperaba 404", code)
+	}
+}
+
+func TestGetAutosFiltrosSinAcentos(t *testing.T) {
+	r, repo := nuevoCatalogo(t)
+	auto := autoPrueba("R0001", "Renault", 12000, false)
+	auto.Transmision = "automática"
+	auto.TipoCombustible = "diésel"
+	if err := repo.Create(context.Background(), auto); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	var pagina paginaPrueba
+	if code := pedir(t, r, "GET", "/api/autos?transmision=automatica", "", &pagina); code != http.StatusOK || pagina.Total != 1 || pagina.Items[0].StockID != "R0001" {
+		t.Errorf("transmision=automatica: status = %d, página = %+v", code, pagina)
+	}
+	pagina = paginaPrueba{}
+	if code := pedir(t, r, "GET", "/api/autos?-tipo_combustible=diesel", "", &pagina); code != http.StatusOK || pagina.Total != 3 {
+		t.Errorf("-tipo_combustible=diesel: status = %d, total = %d, se esperaba 3", code, pagina.Total)
+	}
+	pagina = paginaPrueba{}
+	if code := pedir(t, r, "GET", "/api/autos?combustible=DIÉSEL", "", &pagina); code != http.StatusOK || pagina.Total != 1 {
+		t.Errorf("combustible=DIÉSEL: status = %d, total = %d, se esperaba 1", code, pagina.Total)
+	}
+}
+
+func TestGetAutosBajoPrecioConVentanaDeDescuento(t *testing.T) {
+	r, repo := nuevoCatalogo(t)
+	ctx := context.Background()
+	now := time.Now()
+	desde, hasta := now.Add(-time.Hour), now.Add(time.Hour)
+	vencida := now.Add(-time.Minute)
+
+	// T0002 tiene un descuento programado que ya empezó y no se volvió a guardar
+	abierta, _ := repo.FindByStockID(ctx, "T0002")
+	abierta.Promocion = &models.Promocion{Tipo: models.DescuentoMonto, Valor: 1000, ValidoDesde: &desde, ValidoHasta: &hasta, CreadoEn: now.AddDate(0, 0, -1)}
+	if err := repo.UpdatePrecios(ctx, abierta); err != nil {
+		t.Fatalf("UpdatePrecios: %v", err)
+	}
+	// F0001 bajó de precio por un descuento cuya ventana ya cerró
+	cerrada, _ := repo.FindByStockID(ctx, "F0001")
+	cerrada.Promocion = &models.Promocion{Tipo: models.DescuentoMonto, Valor: 1000, ValidoDesde: &desde, ValidoHasta: &vencida, CreadoEn: desde}
+	cerrada.CambioPrecio(14000, models.MotivoPrecioDescuento, desde)
+	if err := repo.UpdatePrecios(ctx, cerrada); err != nil {
+		t.Fatalf("UpdatePrecios: %v", err)
+	}
+
+	var pagina paginaPrueba
+	if code := pedir(t, r, "GET", "/api/autos?bajo_precio_dias=7", "", &pagina); code != http.StatusOK || pagina.Total != 1 || pagina.Items[0].StockID != "T0002" {
+		t.Fatalf("bajo_precio_dias=7: status = %d, página = %+v", code, pagina)
+	}
+	if pagina.Items[0].Precio != 9000 {
+		t.Errorf("precio con ventana abierta = %v, se esperaba 9000", pagina.Items[0].Precio)
+	}
+
+	var auto models.AutoPublico
+	if code := pedir(t, r, "GET", "/api/autos/F0001", "", &auto); code != http.StatusOK || auto.Precio != 15000 || auto.BajoDePrecio {
+		t.Errorf("auto con ventana cerrada: status = %d, precio = %v, bajo de precio = %v", code, auto.Precio, auto.BajoDePrecio)
+	}
+}
+
+func TestGetAutosPorCursor(t *testing.T) {
+	r, _ := nuevoCatalogo(t)
+
+	vistos := []string{}
+	url := "/api/autos?paginacion=cursor&per_page=2&sort_precio=desc"
+	for {
+		var pagina paginaPrueba
+		if code := pedir(t, r, "GET", url, "", &pagina); code != http.StatusOK {
+			t.Fatalf("status = %d, se esperaba 200", code)
+		}
+		for _, item := range pagina.Items {
+			vistos = append(vistos, item.StockID)
+		}
+		if !pagina.HasMore {
+			break
+		}
+		url = "/api/autos?paginacion=cursor&per_page=2&sort_precio=desc&cursor=" + pagina.NextCursor
+	}
+
+	if strings.Join(vistos, ",") != "T0001,F0001,T0002" {
+		t.Errorf("autos recorridos = %v", vistos)
+	}
+}
+
+func TestGetAuto(t *testing.T) {
+	r, _ := nuevoCatalogo(t)
+
+	var auto models.AutoPublico
+	if code := pedir(t, r, "GET", "/api/autos/F0001", "", &auto); code != http.StatusOK || auto.Marca != "Ford" {
+		t.Errorf("status = %d, auto = %+v", code, auto)
+	}
+	var fallo struct {
+		Error struct {
+			Code    string `json:"code"`
+			Mensaje string `json:"mensaje"`
+		} `json:"error"`
+	}
+	if code := pedir(t, r, "GET", "/api/autos/F0099", "", &fallo); code != http.StatusNotFound {
+		t.Errorf("auto inexistente: status = %d, se esperaba 404", code)
+	}
+	if fallo.Error.Code != "auto_no_encontrado" || fallo.Error.Mensaje != "Auto no encontrado" {
+		t.Errorf("error = %+v", fallo.Error)
+	}
+	if code := pedir(t, r, "GET", "/api/autos/f1", "", nil); code != http.StatusBadRequest {
+		t.Errorf("stock_id inválido: status = %d, se esperaba 400", code)
+	}
+}
+
+// camposPrivados son las claves que no pueden aparecer en ninguna respuesta pública
+var camposPrivados = []string{"reservas", "reservado_por", "vendido_por", "en_negociacion", "en_mantenimiento", "telefono", "celular"}
+
+// buscarCamposPrivados recorre el JSON decodificado y devuelve las rutas de las claves privadas
+func buscarCamposPrivados(valor interface{}, ruta string) []string {
+	var encontrados []string
+	switch v := valor.(type) {
+	case map[string]interface{}:
+		for clave, hijo := range v {
+			for _, privado := range camposPrivados {
+				if clave == privado {
+					encontrados = append(encontrados, ruta+"."+clave)
+				}
+			}
+			encontrados = append(encontrados, buscarCamposPrivados(hijo, ruta+"."+clave)...)
+		}
+	case []interface{}:
+		for _, hijo := range v {
+			encontrados = append(encontrados, buscarCamposPrivados(hijo, ruta+"[]")...)
+		}
+	}
+	return encontrados
+}
+
+func TestRespuestasPublicasSinDatosPrivados(t *testing.T) {
+	r, repo := nuevoCatalogo(t)
+	ctx := context.Background()
+
+	auto, _ := repo.FindByStockID(ctx, "T0001")
+	auto.Estado = models.EstadoReservado
+	auto.Reservas = []models.Reserva{{ID: "A123", Nombre: "Ana", Apellido: "Pérez", Telefono: "1155550000", FechaHora: time.Now()}}
+	auto.ReservadoPor = &models.ReservadoInfo{Nombre: "Ana", Apellido: "Pérez", Celular: "1155550001"}
+	auto.VendidoPor = &models.VendidoInfo{Nombre: "Juan", Apellido: "Gómez", Celular: "1155550002"}
+	auto.EnNegociacion = &models.NegociacionInfo{Nombre: "Luis", Apellido: "Díaz", Celular: "1155550003"}
+	auto.EnMantenimiento = &models.MantenimientoInfo{Taller: "Taller Sur", Mecanico: "Pedro", Celular: "1155550004"}
+	if err := repo.Update(ctx, auto); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	for _, url := range []string{"/api/autos", "/api/autos/destacados", "/api/autos/T0001"} {
+		req := httptest.NewRequest("GET", url, nil)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, se esperaba 200", url, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"T0001"`) {
+			t.Fatalf("%s: la respuesta no incluye el auto sembrado: %s", url, rec.Body.String())
+		}
+		if strings.Contains(rec.Body.String(), "115555000") {
+			t.Errorf("%s: la respuesta incluye un teléfono: %s", url, rec.Body.String())
+		}
+
+		var respuesta interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &respuesta); err != nil {
+			t.Fatalf("%s: respuesta inválida: %v", url, err)
+		}
+		if encontrados := buscarCamposPrivados(respuesta, ""); len(encontrados) > 0 {
+			t.Errorf("%s: la respuesta incluye campos privados %v", url, encontrados)
+		}
+	}
+}
+
+func TestGetAutosDestacados(t *testing.T) {
+	r, _ := nuevoCatalogo(t)
+
+	var destacados []models.AutoPublico
+	if code := pedir(t, r, "GET", "/api/autos/destacados", "", &destacados); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if len(destacados) != 1 || destacados[0].StockID != "T0001" {
+		t.Errorf("destacados = %+v", destacados)
+	}
+}
+
+func TestGetFacetas(t *testing.T) {
+	r, _ := nuevoCatalogo(t)
+
+	var facetas struct {
+		Total int `json:"total"`
+		Marca []struct {
+			Valor    string `json:"valor"`
+			Cantidad int    `json:"cantidad"`
+		} `json:"marca"`
+	}
+	if code := pedir(t, r, "GET", "/api/autos/facetas", "", &facetas); code != http.StatusOK {
+		t.Fatalf("status = %d, se esperaba 200", code)
+	}
+	if facetas.Total != 3 || len(facetas.Marca) != 2 || facetas.Marca[0].Valor != "Toyota" || facetas.Marca[0].Cantidad != 2 {
+		t.Errorf("facetas = %+v", facetas)
+	}
+}
+
+func TestCrearReserva(t *testing.T) {
+	r, repo := nuevoCatalogo(t)
+	body := `{"nombre": "Ana", "apellido": "Pérez", "telefono": "351555", "fecha_hora": "2030-01-02T10:00:00Z"}`
+
+	if code := pedir(t, r, "POST", "/api/autos/T0001/reservations", body, nil); code != http.StatusCreated {
+		t.Fatalf("status = %d, se esperaba 201", code)
+	}
+	auto, _ := repo.FindByStockID(context.Background(), "T0001")
+	if len(auto.Reservas) != 1 || auto.Reservas[0].Nombre != "Ana" {
+		t.Errorf("reservas = %+v", auto.Reservas)
+	}
+
+	if code := pedir(t, r, "POST", "/api/autos/T0001/reservations", body, nil); code != http.StatusConflict {
+		t.Errorf("reserva repetida: status = %d, se esperaba 409", code)
+	}
+	if code := pedir(t, r, "POST", "/api/autos/T0099/reservations", body, nil); code != http.StatusNotFound {
+		t.Errorf("auto inexistente: status = %d, se esperaba 404", code)
+	}
+	if code := pedir(t, r, "POST", "/api/autos/T0001/reservations", `{"nombre": "Ana"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("reserva incompleta: status = %d, se esperaba 400", code)
+	}
+}
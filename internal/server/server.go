@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
@@ -12,6 +13,10 @@ import (
 	_ "github.com/joho/godotenv/autoload"
 
 	"go-gorilla-autos/internal/database"
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/metricas"
+	"go-gorilla-autos/internal/server/handlers/helpers"
+	"go-gorilla-autos/internal/server/routes/operaciones"
 	"go-gorilla-autos/internal/server/routes/private"
 	"go-gorilla-autos/internal/server/routes/public"
 
@@ -30,14 +35,26 @@ func init() {
 type Server struct {
 	port int
 
-	db database.Service
+	db       database.Service
+	autos    repository.AutoRepository
+	metricas *metricas.Metricas
 }
 
-func NewServer() *http.Server {
+// NewServer arma el servidor HTTP sobre la base ya conectada. Cerrar la base al
+// terminar queda a cargo de quien la creó.
+func NewServer(db database.Service) *http.Server {
 	port, _ := strconv.Atoi(os.Getenv("PORT"))
 	newServer := &Server{
-		port: port,
-		db:   database.New(),
+		port:  port,
+		db:    db,
+		autos: repository.NewMongo(db),
+	}
+	newServer.metricas = metricas.New(newServer.autos)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := database.EnsureIndexes(ctx, newServer.db); err != nil {
+		log.Printf("Error creando índices: %v", err)
 	}
 
 	return &http.Server{
@@ -52,8 +69,21 @@ func NewServer() *http.Server {
 func (s *Server) RegisterRoutes() http.Handler {
 	r := mux.NewRouter()
 
-	// Apply CORS middleware
-	r.Use(middleware.CORSMiddleware)
+	middlewares := []mux.MiddlewareFunc{
+		// Asignar el request ID y registrar cada solicitud con su logger
+		middleware.LoggingMiddleware,
+		// Medir todas las solicitudes, incluidas las que rechaza la autenticación
+		middleware.MetricasMiddleware(s.metricas),
+		// Apply CORS middleware
+		middleware.CORSMiddleware,
+	}
+	r.Use(middlewares...)
+
+	// Responder con el formato de error común también a rutas y métodos inexistentes.
+	// r.Use solo corre en las rutas que coinciden, así que estos handlers se envuelven
+	// con los mismos middlewares para que también tengan request ID, log y métricas.
+	r.NotFoundHandler = encadenar(http.HandlerFunc(helpers.NotFoundHandler), middlewares)
+	r.MethodNotAllowedHandler = encadenar(http.HandlerFunc(helpers.MethodNotAllowedHandler), middlewares)
 
 	// Crear un subrouter para rutas privadas
 	privateRouter := r.PathPrefix("/api/admin").Subrouter()
@@ -61,15 +91,29 @@ func (s *Server) RegisterRoutes() http.Handler {
 	// Aplicar middleware de autenticación solo a rutas privadas
 	privateRouter.Use(middleware.AuthMiddlewareFunc)
 
+	// Las escrituras privadas deben declarar quién las hace para el historial
+	privateRouter.Use(middleware.UsuarioMiddleware)
+
+	// Registrar sondas, métricas y versión, fuera de /api y sin autenticación
+	operaciones.RegisterOperacionesRoutes(r, s.db, s.metricas)
+
 	// Registrar rutas públicas
-	public.RegisterPublicRoutes(r, s.db)
+	public.RegisterPublicRoutes(r, s.autos)
 
 	// Registrar rutas privadas
-	private.RegisterPrivateRoutes(privateRouter, s.db)
+	private.RegisterPrivateRoutes(privateRouter, s.autos)
 
 	return r
 }
 
+// encadenar envuelve h con los middlewares en el mismo orden en que los aplica r.Use
+func encadenar(h http.Handler, middlewares []mux.MiddlewareFunc) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
 // Middlewares
 func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
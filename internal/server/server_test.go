@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-gorilla-autos/internal/database/repository"
+	"go-gorilla-autos/internal/metricas"
+)
+
+func TestRutasInexistentesPasanPorLosMiddlewares(t *testing.T) {
+	autos := repository.NewMemoria()
+	s := &Server{autos: autos, metricas: metricas.New(autos)}
+	handler := s.RegisterRoutes()
+
+	casos := []struct {
+		method string
+		url    string
+		status int
+	}{
+		{"GET", "/no-existe", http.StatusNotFound},
+		{"POST", "/healthz", http.StatusMethodNotAllowed},
+	}
+	for _, caso := range casos {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(caso.method, caso.url, nil))
+		if rec.Code != caso.status {
+			t.Fatalf("%s %s: status = %d, se esperaba %d", caso.method, caso.url, rec.Code, caso.status)
+		}
+
+		var respuesta struct {
+			Error struct {
+				RequestID string `json:"request_id"`
+			} `json:"error"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&respuesta); err != nil {
+			t.Fatalf("%s %s: decodificando la respuesta: %v", caso.method, caso.url, err)
+		}
+		requestID := rec.Header().Get("X-Request-ID")
+		if requestID == "" || respuesta.Error.RequestID != requestID {
+			t.Errorf("%s %s: request_id = %q, header = %q", caso.method, caso.url, respuesta.Error.RequestID, requestID)
+		}
+		if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+			t.Errorf("%s %s: faltan los headers de CORS", caso.method, caso.url)
+		}
+	}
+}
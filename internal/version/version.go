@@ -0,0 +1,55 @@
+// Package version expone la información de compilación del binario. Commit y BuildTime
+// se inyectan al compilar con -ldflags; si no se inyectan se toman de la información de
+// VCS que Go guarda en el binario.
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Valores inyectados al compilar, por ejemplo:
+//
+//	go build -ldflags "-X go-gorilla-autos/internal/version.Commit=$(git rev-parse HEAD)"
+var (
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info es la información de compilación del binario
+type Info struct {
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Modified  bool   `json:"modified,omitempty"`
+}
+
+// Get devuelve la información de compilación. Los campos que no se conocen quedan en "unknown".
+func Get() Info {
+	info := Info{Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range build.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildTime == "" {
+					info.BuildTime = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}